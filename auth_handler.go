@@ -1,34 +1,45 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/natnael-alemayehu/chirpy/internal/auth"
 	"github.com/natnael-alemayehu/chirpy/internal/database"
 )
 
+const refreshTokenTTL = 60 * 24 * time.Hour
+
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	type parameter struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
 
 	var param parameter
 	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
 		respondWithError(w, http.StatusBadRequest, "bad request", err)
+		return
+	}
+
+	keys := loginKeys(r, param.Email)
+	if !cfg.checkLoginLockout(w, r, keys) {
+		return
 	}
 
 	usr, err := cfg.db.GetUserByEmail(r.Context(), param.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			// Spend the same time as a real comparison so unknown emails
+			// can't be told apart by how quickly they fail.
+			auth.CheckDummyPassword(param.Password, cfg.passwordParams)
+			cfg.recordLoginFailure(r.Context(), r, keys)
 			respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 			return
 		}
@@ -43,91 +54,241 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !match {
+		cfg.recordLoginFailure(r.Context(), r, keys)
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
-	token, err := auth.MakeJWT(usr.ID, cfg.secret, time.Hour)
+	cfg.rehashPasswordIfNeeded(r.Context(), usr, param.Password)
+
+	// Failures are only cleared once the second factor has been checked
+	// too, so knowing the password doesn't reset the count.
+	if usr.TotpEnabledAt.Valid {
+		cfg.respondWithMFAChallenge(w, usr)
+		return
+	}
+
+	cfg.clearLoginFailures(r.Context(), usr.Email)
+	cfg.respondWithLogin(w, r, usr)
+}
+
+// rehashPasswordIfNeeded re-hashes a correct password whose stored hash was
+// made with other Argon2id parameters, so raising the costs upgrades every
+// account as its owner next logs in. Failures are only logged; the old hash
+// still works.
+func (cfg *apiConfig) rehashPasswordIfNeeded(ctx context.Context, usr database.User, password string) {
+	stale, err := auth.NeedsRehash(usr.HashedPassword, cfg.passwordParams)
+	if err != nil || !stale {
+		return
+	}
+	hash, err := auth.HashPasswordWithParams(password, cfg.passwordParams)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "JWT creation error", err)
+		log.Printf("Couldn't rehash password for user %s: %v", usr.ID, err)
 		return
 	}
+	// Matching on the old hash keeps a password changed meanwhile from
+	// being overwritten.
+	if _, err := cfg.db.RehashUserPassword(ctx, database.RehashUserPasswordParams{
+		NewHash: hash,
+		ID:      usr.ID,
+		OldHash: usr.HashedPassword,
+	}); err != nil {
+		log.Printf("Couldn't store rehashed password for user %s: %v", usr.ID, err)
+	}
+}
 
-	refreshToken, err := auth.MakeRefreshToken()
+// respondWithLogin starts a new session for usr and writes the access and
+// refresh tokens.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, usr database.User) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	token, err := cfg.makeAccessToken(usr.ID, usr.TokenVersion)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Refresh Token Error", err)
+		respondWithError(w, http.StatusInternalServerError, "JWT creation error", err)
 		return
 	}
 
-	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    usr.ID,
-		ExpiresAt: time.Now().Add(1440 * time.Hour),
+	refreshToken, err := cfg.issueRefreshToken(r.Context(), cfg.db, usr.ID, refreshSession{
+		FamilyID:  uuid.New(),
+		StartedAt: time.Now(),
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't save refresh token", err)
+		return
+	}
+
+	user, err := cfg.newUserWithSubscription(r.Context(), usr)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch subscription", err)
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:          usr.ID,
-			CreatedAt:   usr.CreatedAt,
-			UpdatedAt:   usr.UpdatedAt,
-			Email:       usr.Email,
-			IsChirpyRed: usr.IsChirpyRed,
-		},
+		User:         user,
 		Token:        token,
 		RefreshToken: refreshToken,
 	})
-
 }
 
 func (cfg *apiConfig) handlerRefreshToken(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 	reftoken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Ref Token not found in Header", err)
+		return
 	}
 
-	refToken, err := cfg.db.GetUserFromRefreshToken(r.Context(), reftoken)
+	// ConsumeRefreshToken revokes the presented token and returns it in one
+	// statement, so two requests racing with the same token can't both
+	// rotate it.
+	var userID uuid.UUID
+	var tokenVersion int32
+	var newRefreshToken string
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		old, err := q.ConsumeRefreshToken(r.Context(), auth.HashRefreshToken(reftoken))
+		if err != nil {
+			return err
+		}
+		userID = old.UserID
+		tokenVersion, err = q.GetUserTokenVersion(r.Context(), old.UserID)
+		if err != nil {
+			return err
+		}
+		newRefreshToken, err = cfg.issueRefreshToken(r.Context(), q, old.UserID, refreshSession{
+			FamilyID:  old.FamilyID,
+			StartedAt: old.SessionStartedAt,
+			UserAgent: old.UserAgent,
+			IPAddress: old.IpAddress,
+		})
+		return err
+	})
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Ref Token not found", err)
-	}
-
-	if time.Now().After(refToken.ExpiresAt) {
-		respondWithError(w, http.StatusBadRequest, "Refresh token expired", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			cfg.detectRefreshTokenReuse(r.Context(), reftoken)
+			respondWithError(w, http.StatusUnauthorized, "Refresh token is invalid, expired or revoked", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rotate refresh token", err)
 		return
 	}
 
-	oneHour := 3600 * time.Second
-	expirationTime := oneHour
-
-	token, err := auth.MakeJWT(refToken.UserID, cfg.secret, expirationTime)
+	token, err := cfg.makeAccessToken(userID, tokenVersion)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "JWT creation error", err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, response{
-		Token: token,
+		Token:        token,
+		RefreshToken: newRefreshToken,
+	})
+}
+
+// detectRefreshTokenReuse revokes every token in the family when an already
+// revoked token is presented again. Either the legitimate client or an
+// attacker holds a stale copy, and there's no telling which, so the whole
+// session has to go.
+func (cfg *apiConfig) detectRefreshTokenReuse(ctx context.Context, token string) {
+	rt, err := cfg.db.GetRefreshToken(ctx, auth.HashRefreshToken(token))
+	if err != nil || !rt.RevokedAt.Valid {
+		return
+	}
+	if err := cfg.db.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		log.Printf("Couldn't revoke refresh token family %s: %v", rt.FamilyID, err)
+		return
+	}
+	log.Printf("Refresh token %s... reused by user %s; revoked family %s", rt.TokenPrefix, rt.UserID, rt.FamilyID)
+}
+
+// refreshSession is what a refresh token inherits from the login that
+// started its family.
+type refreshSession struct {
+	FamilyID  uuid.UUID
+	StartedAt time.Time
+	UserAgent string
+	IPAddress string
+}
+
+// issueRefreshToken mints a refresh token in session. Login starts a new
+// session; every rotation continues the session of the token it replaces.
+// Only the token's digest and prefix are stored.
+func (cfg *apiConfig) issueRefreshToken(ctx context.Context, q *database.Queries, userID uuid.UUID, session refreshSession) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID:          uuid.New(),
+		TokenHash:   auth.HashRefreshToken(refreshToken),
+		TokenPrefix: auth.RefreshTokenPrefix(refreshToken),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      userID,
+		ExpiresAt:   time.Now().Add(refreshTokenTTL),
+		FamilyID:    session.FamilyID,
+
+		SessionStartedAt: session.StartedAt,
+		LastUsedAt:       time.Now(),
+		UserAgent:        session.UserAgent,
+		IpAddress:        session.IPAddress,
 	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
 }
 
 func (cfg *apiConfig) handlerRevokeRefreshToken(w http.ResponseWriter, r *http.Request) {
 	reftoken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Ref Token not found", err)
+		return
 	}
 
-	err = cfg.db.RevokeRefreshToken(r.Context(), reftoken)
-	if err != nil {
+	// Access tokens don't say which session they belong to, so logging out
+	// bumps the token version and every access token dies with this one.
+	// Other sessions carry on with their refresh tokens.
+	var userID uuid.UUID
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		userID, err = q.RevokeRefreshToken(r.Context(), auth.HashRefreshToken(reftoken))
+		if err != nil {
+			return err
+		}
+		_, err = q.BumpUserTokenVersion(r.Context(), userID)
+		return err
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		respondWithError(w, http.StatusInternalServerError, "Can't revoke Token", err)
 		return
 	}
+	if err == nil {
+		cfg.tokenVersions.forget(userID)
+	}
 
 	respondWithJSON(w, http.StatusNoContent, struct{}{})
 }
+
+// optionalUserID returns the caller's user ID on public endpoints that
+// personalise their output. A missing or invalid token is not an error there;
+// the request is simply treated as anonymous.
+func (cfg *apiConfig) optionalUserID(r *http.Request) uuid.UUID {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.Nil
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/entities"
+)
+
+// indexChirpBody rebuilds the lookup tables derived from a chirp's body. Call
+// it in the same transaction that writes the body so the two never disagree;
+// an empty body (a tombstone) just clears them.
+func indexChirpBody(ctx context.Context, q *database.Queries, chrp database.Chirp) error {
+	if err := indexChirpHashtags(ctx, q, chrp); err != nil {
+		return err
+	}
+	return indexChirpMentions(ctx, q, chrp)
+}
+
+func indexChirpHashtags(ctx context.Context, q *database.Queries, chrp database.Chirp) error {
+	if err := q.DeleteChirpHashtags(ctx, chrp.ID); err != nil {
+		return err
+	}
+
+	hashtags := entities.Hashtags(chrp.Body)
+	if len(hashtags) == 0 {
+		return nil
+	}
+	tags := make([]string, 0, len(hashtags))
+	for _, h := range hashtags {
+		tags = append(tags, h.Tag)
+	}
+
+	return q.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+		ChirpID:   chrp.ID,
+		Tags:      tags,
+		CreatedAt: chrp.CreatedAt,
+	})
+}
+
+// indexChirpMentions stores the @mentions that resolve to an account.
+// Mentions of usernames nobody has are left as plain text.
+func indexChirpMentions(ctx context.Context, q *database.Queries, chrp database.Chirp) error {
+	if err := q.DeleteChirpMentions(ctx, chrp.ID); err != nil {
+		return err
+	}
+
+	mentions := entities.Mentions(chrp.Body)
+	if len(mentions) == 0 {
+		return nil
+	}
+	usernames := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		usernames = append(usernames, entities.NormalizeUsername(m.Username))
+	}
+
+	users, err := q.ListUsersByUsernames(ctx, usernames)
+	if err != nil {
+		return err
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, u := range users {
+		userIDs[entities.NormalizeUsername(u.Username.String)] = u.ID
+	}
+
+	arg := database.AddChirpMentionsParams{
+		ChirpID:   chrp.ID,
+		CreatedAt: chrp.CreatedAt,
+	}
+	for _, m := range mentions {
+		id, ok := userIDs[entities.NormalizeUsername(m.Username)]
+		if !ok {
+			continue
+		}
+		arg.UserIds = append(arg.UserIds, id)
+		arg.StartOffsets = append(arg.StartOffsets, int32(m.Start))
+		arg.EndOffsets = append(arg.EndOffsets, int32(m.End))
+	}
+	if len(arg.UserIds) == 0 {
+		return nil
+	}
+
+	return q.AddChirpMentions(ctx, arg)
+}
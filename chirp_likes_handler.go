@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	chrp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil || chrp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	// Liking twice is a no-op thanks to the (user_id, chirp_id) constraint.
+	_, err = cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:    userID,
+		ChirpID:   chirpID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't like chirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	_, err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unlike chirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
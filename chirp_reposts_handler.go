@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	if err := cfg.checkEmailVerified(r.Context(), userID); err != nil {
+		if errors.Is(err, errEmailNotVerified) {
+			respondWithError(w, http.StatusForbidden, "Verify your email address before posting", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+
+	original, err := cfg.repostTarget(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+	if original.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't rechirp your own chirp", nil)
+		return
+	}
+
+	var chrp database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		chrp, err = q.CreateRechirp(r.Context(), database.CreateRechirpParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			RechirpOf: uuid.NullUUID{UUID: original.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return emitEvent(r.Context(), q, outboxChirpCreated, newChirpApp(chrp))
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "You already rechirped this chirp", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rechirp", err)
+		return
+	}
+	cfg.webhookDispatcher.wake()
+
+	chirpApps, err := cfg.chirpApps(r.Context(), []database.Chirp{chrp}, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rechirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, chirpApps[0])
+}
+
+func (cfg *apiConfig) handlerUndoRechirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		id, err := q.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+			UserID:    userID,
+			RechirpOf: uuid.NullUUID{UUID: chirpID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return emitEvent(r.Context(), q, outboxChirpDeleted, ChirpDeletedEvent{ID: id, UserID: userID})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "You haven't rechirped this chirp", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't undo rechirp", err)
+		return
+	}
+	cfg.webhookDispatcher.wake()
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+const (
+	maxThreadDepth   = 50
+	maxThreadReplies = 500
+)
+
+type ThreadNode struct {
+	ChirpApp
+	Replies []ThreadNode `json:"replies"`
+}
+
+func (cfg *apiConfig) handlerGetChirpThread(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Ancestors []ChirpApp   `json:"ancestors"`
+		Chirp     ChirpApp     `json:"chirp"`
+		Replies   []ThreadNode `json:"replies"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	chrp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "chirp not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "error fetching chirp by id", err)
+		return
+	}
+
+	ancestorRows, err := cfg.db.ListChirpAncestors(r.Context(), database.ListChirpAncestorsParams{
+		ID:       chirpID,
+		MaxDepth: maxThreadDepth,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error fetching thread", err)
+		return
+	}
+
+	descendantRows, err := cfg.db.ListChirpDescendants(r.Context(), database.ListChirpDescendantsParams{
+		ID:       chirpID,
+		MaxDepth: maxThreadDepth,
+		MaxRows:  maxThreadReplies,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error fetching thread", err)
+		return
+	}
+
+	// Convert everything in one batch so reply counts cost a single query.
+	all := make([]database.Chirp, 0, len(ancestorRows)+1+len(descendantRows))
+	for _, v := range ancestorRows {
+		all = append(all, v.Chirp)
+	}
+	all = append(all, chrp)
+	for _, v := range descendantRows {
+		all = append(all, v.Chirp)
+	}
+	apps, err := cfg.chirpApps(r.Context(), all, cfg.optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error fetching thread", err)
+		return
+	}
+
+	ancestors := apps[:len(ancestorRows)]
+	root := apps[len(ancestorRows)]
+	descendants := apps[len(ancestorRows)+1:]
+
+	respondWithJSON(w, http.StatusOK, response{
+		Ancestors: ancestors,
+		Chirp:     root,
+		Replies:   buildReplyTree(root.ID, descendants),
+	})
+}
+
+// buildReplyTree nests replies under their parents. The input is ordered by
+// depth then time, so children come out in the order they were posted.
+func buildReplyTree(rootID string, replies []ChirpApp) []ThreadNode {
+	children := make(map[string][]ChirpApp)
+	for _, c := range replies {
+		children[c.ReplyToID] = append(children[c.ReplyToID], c)
+	}
+
+	var build func(parentID string) []ThreadNode
+	build = func(parentID string) []ThreadNode {
+		nodes := make([]ThreadNode, 0, len(children[parentID]))
+		for _, c := range children[parentID] {
+			nodes = append(nodes, ThreadNode{
+				ChirpApp: c,
+				Replies:  build(c.ID),
+			})
+		}
+		return nodes
+	}
+	return build(rootID)
+}
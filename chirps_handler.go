@@ -1,30 +1,71 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"slices"
-	"sort"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/natnael-alemayehu/chirpy/internal/auth"
 	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/moderation"
 )
 
 type ChirpApp struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Body         string    `json:"body"`
+	UserID       string    `json:"user_id"`
+	Edited       bool      `json:"edited"`
+	ReplyToID    string    `json:"reply_to_id,omitempty"`
+	ReplyCount   int64     `json:"reply_count"`
+	Deleted      bool      `json:"deleted"`
+	LikeCount    int64     `json:"like_count"`
+	LikedByMe    *bool     `json:"liked_by_me,omitempty"`
+	Kind         string    `json:"kind"`
+	OriginalID   string    `json:"original_id,omitempty"`
+	Original     *ChirpApp `json:"original,omitempty"`
+	RechirpCount int64     `json:"rechirp_count"`
+	QuoteCount   int64     `json:"quote_count"`
+	Mentions     []Mention `json:"mentions"`
+}
+
+// Mention is a resolved @username in a chirp body. Start and End are byte
+// offsets into Body covering the '@' and the username as written.
+type Mention struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Start    int32  `json:"start"`
+	End      int32  `json:"end"`
+}
+
+type ChirpRevision struct {
 	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ChirpID   string    `json:"chirp_id"`
 	Body      string    `json:"body"`
-	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
+const (
+	chirpKindChirp   = "chirp"
+	chirpKindRechirp = "rechirp"
+	chirpKindQuote   = "quote"
+)
+
+var (
+	errChirpTooLong  = errors.New("Chirp is too long")
+	errChirpNotOwned = errors.New("chirp belongs to another user")
+	errChirpRejected = errors.New("Chirp violates the content policy")
+)
+
 func (cfg *apiConfig) handlerCreateChirps(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body    string `json:"body"`
+		ReplyTo string `json:"reply_to"`
+		QuoteOf string `json:"quote_of"`
 	}
 
 	var param parameters
@@ -33,51 +74,98 @@ func (cfg *apiConfig) handlerCreateChirps(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	uid, err := cfg.requestUserID(r)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Expect's a Bearer token", err)
+		respondWithError(w, http.StatusUnauthorized, "Token not valid", err)
 		return
 	}
 
-	uid, err := auth.ValidateJWT(token, cfg.secret)
+	if err := cfg.checkEmailVerified(r.Context(), uid); err != nil {
+		if errors.Is(err, errEmailNotVerified) {
+			respondWithError(w, http.StatusForbidden, "Verify your email address before posting", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+
+	ent, err := cfg.requestEntitlements(r, uid)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Token not valid", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch plan", err)
+		return
+	}
+	moderated, err := cfg.validateChirpBody(param.Body, ent.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	chripBody := validateChirpBody(w, param.Body)
+	replyTo := uuid.NullUUID{}
+	if param.ReplyTo != "" {
+		parentID, err := uuid.Parse(param.ReplyTo)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid reply_to chirp ID", err)
+			return
+		}
+		parent, err := cfg.repostTarget(r.Context(), parentID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "reply_to chirp not found", err)
+			return
+		}
+		replyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
 
-	chrp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Body:      chripBody,
-		UserID:    uid,
+	kind := chirpKindChirp
+	quoteOf := uuid.NullUUID{}
+	if param.QuoteOf != "" {
+		originalID, err := uuid.Parse(param.QuoteOf)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid quote_of chirp ID", err)
+			return
+		}
+		original, err := cfg.repostTarget(r.Context(), originalID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "quote_of chirp not found", err)
+			return
+		}
+		kind = chirpKindQuote
+		quoteOf = uuid.NullUUID{UUID: original.ID, Valid: true}
+	}
+
+	var chrp database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		chrp, err = createChirp(r.Context(), q, database.CreateChirpParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    uid,
+			ReplyToID: replyTo,
+			Kind:      kind,
+			QuoteOf:   quoteOf,
+		}, moderated)
+		return err
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Create chirp error", err)
 		return
 	}
+	cfg.webhookDispatcher.wake()
 
-	respondWithJSON(w, http.StatusCreated, ChirpApp{
-		ID:        chrp.ID.String(),
-		CreatedAt: chrp.CreatedAt,
-		UpdatedAt: chrp.UpdatedAt,
-		Body:      chrp.Body,
-		UserID:    chrp.UserID.String(),
-	})
+	chirpApps, err := cfg.chirpApps(r.Context(), []database.Chirp{chrp}, uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Create chirp error", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, chirpApps[0])
 
 }
 
 func (cfg *apiConfig) handlerListChirps(w http.ResponseWriter, r *http.Request) {
-	chrps, err := cfg.db.ListChirps(r.Context())
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "list chirps error", err)
-	}
-
 	authorID := uuid.Nil
 	authorIDString := r.URL.Query().Get("author_id")
 	if authorIDString != "" {
+		var err error
 		authorID, err = uuid.Parse(authorIDString)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
@@ -85,36 +173,68 @@ func (cfg *apiConfig) handlerListChirps(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	sortDirection := "asc"
-	sortDirectionParam := r.URL.Query().Get("sort")
-	if sortDirectionParam == "desc" {
-		sortDirection = "desc"
+	desc := r.URL.Query().Get("sort") == "desc"
+
+	limit, cursor, err := parsePageParams(r, desc)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
 	}
 
-	chirpApps := []ChirpApp{}
-	for _, v := range chrps {
-		if authorID != uuid.Nil && v.UserID != authorID {
-			continue
-		}
-		chirpApps = append(chirpApps, ChirpApp{
-			ID:        v.ID.String(),
-			CreatedAt: v.CreatedAt,
-			UpdatedAt: v.UpdatedAt,
-			Body:      v.Body,
-			UserID:    v.UserID.String(),
-		})
+	chrps, err := cfg.listChirpsPage(r.Context(), authorID, desc, cursor, limit+1)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list chirps error", err)
+		return
 	}
 
-	sort.Slice(chirpApps, func(i, j int) bool {
-		if sortDirection == "desc" {
-			return chirpApps[i].CreatedAt.After(chirpApps[j].CreatedAt)
-		}
-		return chirpApps[i].CreatedAt.Before(chirpApps[j].CreatedAt)
-	})
+	if len(chrps) > int(limit) {
+		chrps = chrps[:limit]
+		last := chrps[len(chrps)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	chirpApps, err := cfg.chirpApps(r.Context(), chrps, cfg.optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list chirps error", err)
+		return
+	}
 
 	respondWithJSON(w, http.StatusOK, chirpApps)
 }
 
+// listChirpsPage picks the keyset query matching the author filter and sort
+// direction so the database does the filtering, ordering and limiting.
+func (cfg *apiConfig) listChirpsPage(ctx context.Context, authorID uuid.UUID, desc bool, cursor pageCursor, limit int32) ([]database.Chirp, error) {
+	switch {
+	case authorID != uuid.Nil && desc:
+		return cfg.db.ListChirpsByAuthorDesc(ctx, database.ListChirpsByAuthorDescParams{
+			UserID:          authorID,
+			BeforeCreatedAt: cursor.CreatedAt,
+			BeforeID:        cursor.ID,
+			PageLimit:       limit,
+		})
+	case authorID != uuid.Nil:
+		return cfg.db.ListChirpsByAuthorAsc(ctx, database.ListChirpsByAuthorAscParams{
+			UserID:         authorID,
+			AfterCreatedAt: cursor.CreatedAt,
+			AfterID:        cursor.ID,
+			PageLimit:      limit,
+		})
+	case desc:
+		return cfg.db.ListChirpsDesc(ctx, database.ListChirpsDescParams{
+			BeforeCreatedAt: cursor.CreatedAt,
+			BeforeID:        cursor.ID,
+			PageLimit:       limit,
+		})
+	default:
+		return cfg.db.ListChirpsAsc(ctx, database.ListChirpsAscParams{
+			AfterCreatedAt: cursor.CreatedAt,
+			AfterID:        cursor.ID,
+			PageLimit:      limit,
+		})
+	}
+}
+
 func (cfg *apiConfig) handlerGetChirpsByID(w http.ResponseWriter, r *http.Request) {
 	paramChirpID := r.PathValue("chirpID")
 
@@ -134,36 +254,152 @@ func (cfg *apiConfig) handlerGetChirpsByID(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, ChirpApp{
-		ID:        chrp.ID.String(),
-		CreatedAt: chrp.CreatedAt,
-		UpdatedAt: chrp.UpdatedAt,
-		Body:      chrp.Body,
-		UserID:    chrp.UserID.String(),
+	chirpApps, err := cfg.chirpApps(r.Context(), []database.Chirp{chrp}, cfg.optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error fetching chirp by id", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpApps[0])
+}
+
+func (cfg *apiConfig) handlerUpdateChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+
+	ent, err := cfg.requestEntitlements(r, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch plan", err)
+		return
+	}
+	moderated, err := cfg.validateChirpBody(param.Body, ent.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var updated database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		current, err := q.GetChirpByIDForUpdate(r.Context(), chirpID)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt.Valid || current.Kind == chirpKindRechirp {
+			return sql.ErrNoRows
+		}
+		if current.UserID != userID {
+			return errChirpNotOwned
+		}
+
+		_, err = q.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ID:        uuid.New(),
+			ChirpID:   current.ID,
+			Body:      current.Body,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		updated, err = q.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:   current.ID,
+			Body: moderated.Text,
+		})
+		if err != nil {
+			return err
+		}
+		if err := flagChirp(r.Context(), q, updated.ID, moderated); err != nil {
+			return err
+		}
+		return indexChirpBody(r.Context(), q, updated)
 	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondWithError(w, http.StatusNotFound, "chirp not found", err)
+		case errors.Is(err, errChirpNotOwned):
+			respondWithError(w, http.StatusForbidden, "You can't edit this chirp", err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newChirpApp(updated))
 }
 
-func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
-	chirpIDString := r.PathValue("chirpID")
-	chirpID, err := uuid.Parse(chirpIDString)
+func (cfg *apiConfig) handlerListChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	// A deleted chirp's earlier bodies are gone with it.
+	dbChirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "error fetching chirp by id", err)
+		return
+	}
+	if err != nil || dbChirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	revisions, err := cfg.db.ListChirpRevisions(r.Context(), chirpID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		respondWithError(w, http.StatusInternalServerError, "list revisions error", err)
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.secret)
+
+	out := make([]ChirpRevision, 0, len(revisions))
+	for _, v := range revisions {
+		out = append(out, ChirpRevision{
+			ID:        v.ID.String(),
+			ChirpID:   v.ChirpID.String(),
+			Body:      v.Body,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, out)
+}
+
+func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	chirpIDString := r.PathValue("chirpID")
+	chirpID, err := uuid.Parse(chirpIDString)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	userID, err := cfg.authenticate(r)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
 		return
 	}
 
 	dbChirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || dbChirp.DeletedAt.Valid {
 		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
 		return
 	}
@@ -172,43 +408,258 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = cfg.db.DeleteChirp(r.Context(), chirpID)
+	// A chirp with replies is blanked out instead of removed so the rest of
+	// the conversation keeps its place in the thread.
+	hasReplies, err := cfg.db.ChirpHasReplies(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
+		return
+	}
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		// Plain rechirps go with the chirp. They're deleted here rather than
+		// left to the cascade so each one gets its own chirp.deleted event.
+		rechirps, err := q.DeleteRechirpsOf(r.Context(), uuid.NullUUID{UUID: chirpID, Valid: true})
+		if err != nil {
+			return err
+		}
+		for _, rc := range rechirps {
+			if err := emitEvent(r.Context(), q, outboxChirpDeleted, ChirpDeletedEvent{ID: rc.ID, UserID: rc.UserID}); err != nil {
+				return err
+			}
+		}
+		if hasReplies {
+			tombstone, err := q.TombstoneChirp(r.Context(), chirpID)
+			if err != nil {
+				return err
+			}
+			if err := indexChirpBody(r.Context(), q, tombstone); err != nil {
+				return err
+			}
+		} else if err := q.DeleteChirp(r.Context(), chirpID); err != nil {
+			return err
+		}
+		return emitEvent(r.Context(), q, outboxChirpDeleted, ChirpDeletedEvent{ID: chirpID, UserID: userID})
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
 		return
 	}
+	cfg.webhookDispatcher.wake()
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // HELPERS
 // ============================================
-func validateChirpBody(w http.ResponseWriter, body string) (clean string) {
-	badwords := []string{"kerfuffle", "sharbert", "fornax"}
+func newChirpApp(c database.Chirp) ChirpApp {
+	app := ChirpApp{
+		ID:        c.ID.String(),
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body:      c.Body,
+		UserID:    c.UserID.String(),
+		Edited:    c.EditedAt.Valid,
+		Deleted:   c.DeletedAt.Valid,
+		Kind:      c.Kind,
+		Mentions:  []Mention{},
+	}
+	if c.ReplyToID.Valid {
+		app.ReplyToID = c.ReplyToID.UUID.String()
+	}
+	if c.RechirpOf.Valid {
+		app.OriginalID = c.RechirpOf.UUID.String()
+	}
+	if c.QuoteOf.Valid {
+		app.OriginalID = c.QuoteOf.UUID.String()
+	}
+	if app.Deleted {
+		app.UserID = ""
+	}
+	return app
+}
 
-	final_output := getCleanedBody(body, badwords)
+// chirpApps converts a page of chirps, fills in the per-chirp counters and
+// embeds the original of every rechirp and quote (one level deep). viewerID
+// is the authenticated caller, or uuid.Nil for anonymous requests, in which
+// case the per-viewer fields are left out.
+func (cfg *apiConfig) chirpApps(ctx context.Context, chrps []database.Chirp, viewerID uuid.UUID) ([]ChirpApp, error) {
+	apps, err := cfg.chirpAppsWithCounters(ctx, chrps, viewerID)
+	if err != nil {
+		return nil, err
+	}
 
-	const maxChirpLength = 140
-	if len(body) > maxChirpLength {
-		respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
-		return
+	originalIDs := make([]uuid.UUID, 0)
+	for _, c := range chrps {
+		if c.RechirpOf.Valid {
+			originalIDs = append(originalIDs, c.RechirpOf.UUID)
+		}
+		if c.QuoteOf.Valid {
+			originalIDs = append(originalIDs, c.QuoteOf.UUID)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return apps, nil
 	}
 
-	return final_output
+	originals, err := cfg.db.ListChirpsByIDs(ctx, originalIDs)
+	if err != nil {
+		return nil, err
+	}
+	originalApps, err := cfg.chirpAppsWithCounters(ctx, originals, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]ChirpApp, len(originalApps))
+	for _, o := range originalApps {
+		byID[o.ID] = o
+	}
+
+	// A quote whose original was deleted keeps OriginalID empty (the FK was
+	// nulled) and simply has no embedded original.
+	for i := range apps {
+		if o, ok := byID[apps[i].OriginalID]; ok {
+			apps[i].Original = &o
+		}
+	}
+	return apps, nil
 }
 
-func getCleanedBody(body string, badwords []string) string {
-	lst := strings.Fields(body)
+// chirpAppsWithCounters converts chirps and fills in the per-chirp counters
+// with one batched query each instead of one query per chirp.
+func (cfg *apiConfig) chirpAppsWithCounters(ctx context.Context, chrps []database.Chirp, viewerID uuid.UUID) ([]ChirpApp, error) {
+	apps := make([]ChirpApp, 0, len(chrps))
+	ids := make([]uuid.UUID, 0, len(chrps))
+	for _, c := range chrps {
+		apps = append(apps, newChirpApp(c))
+		ids = append(ids, c.ID)
+	}
+	if len(ids) == 0 {
+		return apps, nil
+	}
 
-	output := []string{}
-	for _, v := range lst {
-		word := strings.ToLower(v)
-		if slices.Contains(badwords, word) {
-			output = append(output, "****")
-			continue
+	counts, err := cfg.db.CountRepliesForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	replyCounts := make(map[uuid.UUID]int64, len(counts))
+	for _, c := range counts {
+		replyCounts[c.ChirpID] = c.ReplyCount
+	}
+
+	likes, err := cfg.db.CountLikesForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likeCounts := make(map[uuid.UUID]int64, len(likes))
+	for _, l := range likes {
+		likeCounts[l.ChirpID] = l.LikeCount
+	}
+
+	reposts, err := cfg.db.CountRepostsForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	repostCounts := make(map[uuid.UUID]database.CountRepostsForChirpsRow, len(reposts))
+	for _, rp := range reposts {
+		repostCounts[rp.ChirpID] = rp
+	}
+
+	mentions, err := cfg.db.ListMentionsForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentionsByChirp := make(map[uuid.UUID][]Mention)
+	for _, m := range mentions {
+		mentionsByChirp[m.ChirpID] = append(mentionsByChirp[m.ChirpID], Mention{
+			UserID:   m.UserID.String(),
+			Username: m.Username.String,
+			Start:    m.StartOffset,
+			End:      m.EndOffset,
+		})
+	}
+
+	var liked map[uuid.UUID]bool
+	if viewerID != uuid.Nil {
+		likedIDs, err := cfg.db.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		liked = make(map[uuid.UUID]bool, len(likedIDs))
+		for _, id := range likedIDs {
+			liked[id] = true
+		}
+	}
+
+	for i := range apps {
+		apps[i].ReplyCount = replyCounts[ids[i]]
+		apps[i].LikeCount = likeCounts[ids[i]]
+		apps[i].RechirpCount = repostCounts[ids[i]].RechirpCount
+		apps[i].QuoteCount = repostCounts[ids[i]].QuoteCount
+		if m, ok := mentionsByChirp[ids[i]]; ok {
+			apps[i].Mentions = m
+		}
+		if liked != nil {
+			likedByMe := liked[ids[i]]
+			apps[i].LikedByMe = &likedByMe
+		}
+	}
+	return apps, nil
+}
+
+// repostTarget loads the chirp a reply, rechirp or quote should point at.
+// Tombstones are not found, and a plain rechirp resolves to its original so
+// reposts never chain.
+func (cfg *apiConfig) repostTarget(ctx context.Context, chirpID uuid.UUID) (database.Chirp, error) {
+	chrp, err := cfg.db.GetChirpByID(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if chrp.Kind == chirpKindRechirp {
+		chrp, err = cfg.db.GetChirpByID(ctx, chrp.RechirpOf.UUID)
+		if err != nil {
+			return database.Chirp{}, err
 		}
-		output = append(output, v)
 	}
+	if chrp.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chrp, nil
+}
+
+// createChirp inserts a chirp with the moderated body, flags and indexes it,
+// and writes chirp.created to the outbox. q must be a transaction.
+func createChirp(ctx context.Context, q *database.Queries, params database.CreateChirpParams, moderated moderation.Result) (database.Chirp, error) {
+	params.Body = moderated.Text
+	chrp, err := q.CreateChirp(ctx, params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if err := flagChirp(ctx, q, chrp.ID, moderated); err != nil {
+		return database.Chirp{}, err
+	}
+	if err := indexChirpBody(ctx, q, chrp); err != nil {
+		return database.Chirp{}, err
+	}
+	if err := emitEvent(ctx, q, outboxChirpCreated, newChirpApp(chrp)); err != nil {
+		return database.Chirp{}, err
+	}
+	return chrp, nil
+}
 
-	return strings.Join(output, " ")
+// validateChirpBody enforces the length limit of the author's plan and runs
+// the body through the moderation chain. The returned result carries the
+// masked text and whether the chirp needs to be flagged for review.
+func (cfg *apiConfig) validateChirpBody(body string, maxChirpLength int) (moderation.Result, error) {
+	if len(body) > maxChirpLength {
+		return moderation.Result{}, errChirpTooLong
+	}
+
+	res := cfg.moderator.Load().Moderate(body)
+	if res.Rejected {
+		return moderation.Result{}, errChirpRejected
+	}
+	return res, nil
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestListChirpRevisions(t *testing.T) {
+	chirpID := uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		deletedAt driver.Value
+		want      int
+		wantRevs  int
+	}{
+		{name: "Live chirp", want: http.StatusOK, wantRevs: 1},
+		{name: "Deleted chirp", deletedAt: now, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := map[string]fakeQuery{
+				"GetChirpByID": func([]driver.Value) (fakeResult, error) {
+					return fakeRow(chirpID.String(), now, now, "", uuid.New().String(), nil,
+						now, nil, tt.deletedAt, chirpKindChirp, nil, nil), nil
+				},
+				"ListChirpRevisions": func([]driver.Value) (fakeResult, error) {
+					return fakeRow(uuid.New().String(), chirpID.String(), "first draft", now), nil
+				},
+			}
+			cfg := newFakeDB(t, queries)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirpID.String()+"/revisions", nil)
+			req.SetPathValue("chirpID", chirpID.String())
+			rec := httptest.NewRecorder()
+			cfg.handlerListChirpRevisions(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var revs []ChirpRevision
+			if err := json.NewDecoder(rec.Body).Decode(&revs); err != nil {
+				t.Fatal(err)
+			}
+			if len(revs) != tt.wantRevs {
+				t.Errorf("got %d revisions, want %d", len(revs), tt.wantRevs)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// ts_headline wraps matches in these control characters so the body can be
+// HTML-escaped before they are swapped for <mark> tags.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+type ChirpSearchResult struct {
+	ChirpApp
+	Rank      float32 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing search query", nil)
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	// Results are ordered by relevance unless the caller asks for a date sort.
+	sortOrder := "rank"
+	switch r.URL.Query().Get("sort") {
+	case "asc":
+		sortOrder = "asc"
+	case "desc":
+		sortOrder = "desc"
+	}
+
+	limit := int32(defaultPageLimit)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "limit must be a positive integer", err)
+			return
+		}
+		limit = int32(min(n, maxPageLimit))
+	}
+
+	rows, err := cfg.db.SearchChirps(r.Context(), database.SearchChirpsParams{
+		HeadlineOptions: "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true",
+		Query:           query,
+		AuthorID:        authorID,
+		SortOrder:       sortOrder,
+		PageLimit:       limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "search chirps error", err)
+		return
+	}
+
+	chrps := make([]database.Chirp, 0, len(rows))
+	for _, v := range rows {
+		chrps = append(chrps, v.Chirp)
+	}
+	apps, err := cfg.chirpApps(r.Context(), chrps, cfg.optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "search chirps error", err)
+		return
+	}
+
+	results := make([]ChirpSearchResult, 0, len(rows))
+	for i, v := range rows {
+		results = append(results, ChirpSearchResult{
+			ChirpApp:  apps[i],
+			Rank:      v.Rank,
+			Highlight: renderHighlight(v.Headline),
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func renderHighlight(headline string) string {
+	escaped := html.EscapeString(headline)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(escaped)
+}
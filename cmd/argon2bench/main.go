@@ -0,0 +1,35 @@
+// Command argon2bench suggests Argon2id parameters for this host. Run it on
+// the machines that serve logins and copy its output into the environment:
+//
+//	go run ./cmd/argon2bench -target 250ms -memory 65536
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+)
+
+func main() {
+	target := flag.Duration("target", 250*time.Millisecond, "how long one password hash should take")
+	memory := flag.Uint("memory", 64*1024, "memory per hash in KiB")
+	parallelism := flag.Uint("parallelism", uint(auth.DefaultPasswordParams().Parallelism), "lanes per hash")
+	flag.Parse()
+
+	if *memory > 1<<32-1 || *parallelism > 255 {
+		log.Fatal("memory must fit in 32 bits and parallelism in 8 bits")
+	}
+
+	params, took, err := auth.SuggestPasswordParams(*target, uint32(*memory), uint8(*parallelism))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("# one hash took %s (target %s)\n", took.Round(time.Millisecond), *target)
+	fmt.Printf("ARGON2_MEMORY_KIB=%d\n", params.Memory)
+	fmt.Printf("ARGON2_ITERATIONS=%d\n", params.Iterations)
+	fmt.Printf("ARGON2_PARALLELISM=%d\n", params.Parallelism)
+}
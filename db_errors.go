@@ -0,0 +1,13 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/mailer"
+)
+
+const (
+	userTokenEmailVerification = "email_verification"
+	emailVerificationTTL       = 24 * time.Hour
+)
+
+var errEmailNotVerified = errors.New("email address is not verified")
+
+// sendEmailVerification issues a fresh verification token and mails it to
+// the user.
+func (cfg *apiConfig) sendEmailVerification(ctx context.Context, usr database.User) error {
+	token, err := cfg.issueUserToken(ctx, usr.ID, userTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	cfg.sendMailAsync(mailer.Message{
+		To:      usr.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Welcome to Chirpy!\n\n"+
+			"Confirm this address by sending the token below to POST /api/users/verify as {\"token\": \"...\"}:\n\n"+
+			"%s\n\nThe token expires in %s. If you didn't sign up, ignore this email.\n",
+			token, emailVerificationTTL),
+	})
+	return nil
+}
+
+// checkEmailVerified enforces REQUIRE_VERIFIED_EMAIL for actions that
+// unverified accounts may not take.
+func (cfg *apiConfig) checkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	if !cfg.requireVerifiedEmail {
+		return nil
+	}
+	usr, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !usr.EmailVerifiedAt.Valid {
+		return errEmailNotVerified
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+	if param.Token == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing token", nil)
+		return
+	}
+
+	var usr database.User
+	err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+		tok, err := q.ConsumeUserToken(r.Context(), database.ConsumeUserTokenParams{
+			TokenHash: auth.HashOneTimeToken(param.Token),
+			Purpose:   userTokenEmailVerification,
+		})
+		if err != nil {
+			return err
+		}
+		usr, err = q.MarkEmailVerified(r.Context(), tok.UserID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, "Verification token is invalid or expired", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+
+	user, err := cfg.newUserWithSubscription(r.Context(), usr)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch subscription", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+func (cfg *apiConfig) handlerResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	usr, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+	if usr.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email is already verified", nil)
+		return
+	}
+
+	if err := cfg.sendEmailVerification(r.Context(), usr); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send verification email", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/entitlements"
+)
+
+// loadEntitlements reads ENTITLEMENTS_FILE, or uses the built-in plans when
+// it is unset.
+func loadEntitlements() (*entitlements.Config, error) {
+	path := os.Getenv("ENTITLEMENTS_FILE")
+	if path == "" {
+		return entitlements.Default(), nil
+	}
+	return entitlements.LoadFile(path)
+}
+
+// entitlementsFor returns what the user's current plan allows.
+func (cfg *apiConfig) entitlementsFor(ctx context.Context, userID uuid.UUID) (entitlements.Entitlements, error) {
+	plan, err := cfg.userPlan(ctx, userID)
+	if err != nil {
+		return entitlements.Entitlements{}, err
+	}
+	return cfg.planEntitlements(plan), nil
+}
+
+// unmappedPlans records the paid plans already reported by
+// planEntitlements, so each is logged once rather than on every request.
+var unmappedPlans sync.Map
+
+// planEntitlements is Config.For, but complains when a paid plan maps to
+// nothing and its subscriber is left with the free plan's limits.
+func (cfg *apiConfig) planEntitlements(plan string) entitlements.Entitlements {
+	if _, ok := cfg.entitlements.Resolve(plan); !ok && plan != entitlements.PlanFree {
+		if _, seen := unmappedPlans.LoadOrStore(plan, true); !seen {
+			log.Printf("ERROR: plan %q isn't in the entitlements config; its subscribers get the free plan's limits. Add it to plans or aliases, or set paid_fallback", plan)
+		}
+	}
+	return cfg.entitlements.For(plan)
+}
+
+// featureDenied is the message for a user whose plan lacks a feature.
+var featureDenied = map[entitlements.Feature]string{
+	entitlements.FeatureEditChirps:      "Editing chirps requires Chirpy Red",
+	entitlements.FeatureScheduledChirps: "Scheduling chirps requires Chirpy Red",
+}
+
+// requestPlanKey is the context key for the requestPlan the plan
+// middlewares resolved.
+type requestPlanKey struct{}
+
+// requestPlan is the caller and their entitlements, resolved once per
+// request by the first middleware that needs them.
+type requestPlan struct {
+	userID uuid.UUID
+	ent    entitlements.Entitlements
+}
+
+func withRequestPlan(r *http.Request, p requestPlan) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestPlanKey{}, p))
+}
+
+// requestUserID is authenticate, reusing the user a plan middleware has
+// already authenticated for this request.
+func (cfg *apiConfig) requestUserID(r *http.Request) (uuid.UUID, error) {
+	if p, ok := r.Context().Value(requestPlanKey{}).(requestPlan); ok {
+		return p.userID, nil
+	}
+	return cfg.authenticate(r)
+}
+
+// requestEntitlements is entitlementsFor, reusing what a plan middleware
+// has already looked up for this request.
+func (cfg *apiConfig) requestEntitlements(r *http.Request, userID uuid.UUID) (entitlements.Entitlements, error) {
+	if p, ok := r.Context().Value(requestPlanKey{}).(requestPlan); ok && p.userID == userID {
+		return p.ent, nil
+	}
+	return cfg.entitlementsFor(r.Context(), userID)
+}
+
+// resolveRequestPlan authenticates the caller and looks up their plan.
+// Without a valid token it reports false and leaves the request to the
+// handler, so clients still get its usual 401.
+func (cfg *apiConfig) resolveRequestPlan(r *http.Request) (requestPlan, bool, error) {
+	userID, err := cfg.requestUserID(r)
+	if err != nil {
+		return requestPlan{}, false, nil
+	}
+	ent, err := cfg.requestEntitlements(r, userID)
+	if err != nil {
+		return requestPlan{}, false, err
+	}
+	return requestPlan{userID: userID, ent: ent}, true, nil
+}
+
+// middlewareRequireEntitlement only lets through users whose plan includes
+// f. Requests without a valid token are passed on for the handler to
+// reject, so clients still get its usual 401.
+func (cfg *apiConfig) middlewareRequireEntitlement(f entitlements.Feature, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok, err := cfg.resolveRequestPlan(r)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't fetch plan", err)
+			return
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !p.ent.Has(f) {
+			respondWithError(w, http.StatusForbidden, featureDenied[f], nil)
+			return
+		}
+		next.ServeHTTP(w, withRequestPlan(r, p))
+	})
+}
+
+// middlewareChirpRateLimit limits how fast a user creates chirps, at the
+// rate their plan allows. A slot is reserved before the handler runs, so
+// concurrent requests can't all slip under the limit, and handed back if
+// the handler rejects the request: a chirp refused as too long or a
+// duplicate rechirp costs nothing.
+func (cfg *apiConfig) middlewareChirpRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok, err := cfg.resolveRequestPlan(r)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't fetch plan", err)
+			return
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := p.userID.String()
+		if ok, wait := cfg.chirpLimiter.Allow(key, p.ent.ChirpsPerHour); !ok {
+			w.Header().Set("Retry-After", fmt.Sprint(int(max(math.Ceil(wait.Seconds()), 1))))
+			respondWithError(w, http.StatusTooManyRequests, "Too many chirps, try again later", nil)
+			return
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, withRequestPlan(r, p))
+		if rec.status < 200 || rec.status >= 300 {
+			cfg.chirpLimiter.Refund(key, p.ent.ChirpsPerHour)
+		}
+	})
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerGetMyEntitlements(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Plan string `json:"plan"`
+		entitlements.Entitlements
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	plan, err := cfg.userPlan(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch plan", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{Plan: plan, Entitlements: cfg.planEntitlements(plan)})
+}
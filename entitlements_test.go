@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/entitlements"
+)
+
+func TestMiddlewareChirpRateLimit(t *testing.T) {
+	queries := map[string]fakeQuery{
+		"GetUserTokenVersion":   func([]driver.Value) (fakeResult, error) { return fakeRow(int64(0)), nil },
+		"GetSubscriptionByUser": func([]driver.Value) (fakeResult, error) { return fakeNoRows(9), nil },
+	}
+	cfg := newFakeDB(t, queries)
+	cfg.jwtKeys = auth.NewHMACKeySet("secret")
+	cfg.entitlements = &entitlements.Config{Plans: map[string]entitlements.Entitlements{
+		entitlements.PlanFree: {MaxChirpLength: 140, ChirpsPerHour: 2},
+	}}
+	cfg.chirpLimiter = entitlements.NewLimiter(time.Hour)
+
+	userID := uuid.New()
+	token, err := cfg.jwtKeys.MakeAccessToken(userID, 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var status int
+	h := cfg.middlewareChirpRateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The handler gets the caller from the middleware without
+		// authenticating again.
+		if got, err := cfg.requestUserID(r); err != nil || got != userID {
+			t.Errorf("requestUserID() = %v, %v; want %v", got, err, userID)
+		}
+		w.WriteHeader(status)
+	}))
+
+	tests := []struct {
+		name    string
+		handler int
+		want    int
+	}{
+		{"Rejected chirps are refunded", http.StatusBadRequest, http.StatusBadRequest},
+		{"Still refunded", http.StatusBadRequest, http.StatusBadRequest},
+		{"First accepted", http.StatusCreated, http.StatusCreated},
+		{"Second accepted", http.StatusCreated, http.StatusCreated},
+		{"Over the limit", http.StatusCreated, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		status = tt.handler
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
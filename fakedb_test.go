@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// fakeResult is what a fake query returns: rows for queries that read,
+// affected for ones that don't.
+type fakeResult struct {
+	cols     []string
+	rows     [][]driver.Value
+	affected int64
+}
+
+// fakeQuery answers one generated query, keyed by its sqlc name.
+type fakeQuery func(args []driver.Value) (fakeResult, error)
+
+// fakeDB is a database/sql driver that answers the generated queries from
+// Go functions, so handlers can be tested without Postgres. Queries
+// without a handler fail the call.
+type fakeDB struct {
+	mu      sync.Mutex
+	queries map[string]fakeQuery
+}
+
+var queryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// newFakeDB returns a config whose db and dbConn both go through queries.
+func newFakeDB(t *testing.T, queries map[string]fakeQuery) *apiConfig {
+	t.Helper()
+	f := &fakeDB{queries: queries}
+	conn := sql.OpenDB(f)
+	t.Cleanup(func() { conn.Close() })
+	return &apiConfig{
+		db:            database.New(conn),
+		dbConn:        conn,
+		tokenVersions: newTokenVersionCache(0),
+	}
+}
+
+func (f *fakeDB) run(query string, args []driver.NamedValue) (fakeResult, error) {
+	m := queryName.FindStringSubmatch(query)
+	if m == nil {
+		return fakeResult{}, fmt.Errorf("fake db: unnamed query %q", query)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q, ok := f.queries[m[1]]
+	if !ok {
+		return fakeResult{}, fmt.Errorf("fake db: unexpected query %s", m[1])
+	}
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	return q(values)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{f} }
+
+type fakeDriver struct{ f *fakeDB }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return fakeConn(d), nil }
+
+type fakeConn struct{ f *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake db: prepared statements aren't supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.f.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{cols: res.cols, rows: res.rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.f.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeRow is a one-row result with a column per value.
+func fakeRow(values ...driver.Value) fakeResult {
+	cols := make([]string, len(values))
+	for i := range cols {
+		cols[i] = fmt.Sprint("col", i)
+	}
+	return fakeResult{cols: cols, rows: [][]driver.Value{values}}
+}
+
+// fakeNoRows is an empty result, which QueryRow reports as sql.ErrNoRows.
+func fakeNoRows(ncols int) fakeResult {
+	return fakeResult{cols: make([]string, ncols)}
+}
+
+// fakeNull turns an invalid nullable into SQL NULL.
+func fakeNull(v driver.Value, valid bool) driver.Value {
+	if !valid {
+		return nil
+	}
+	return v
+}
+
+func fakeUserRow(u database.User) fakeResult {
+	return fakeRow(
+		u.ID.String(), u.CreatedAt, u.UpdatedAt, u.Email, u.HashedPassword,
+		fakeNull(u.Username.String, u.Username.Valid),
+		int64(u.TokenVersion),
+		fakeNull(u.EmailVerifiedAt.Time, u.EmailVerifiedAt.Valid),
+		fakeNull(u.TotpSecret.String, u.TotpSecret.Valid),
+		fakeNull(u.TotpEnabledAt.Time, u.TotpEnabledAt.Valid),
+		u.TotpLastStep,
+	)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	if _, err := cfg.db.GetUserByID(r.Context(), followeeID); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "user not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+
+	_, err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	_, err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.db.ListFollowers(r.Context(), database.ListFollowersParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageLimit:       limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list followers error", err)
+		return
+	}
+
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.FollowerID})
+	}
+
+	followers := make([]Follow, 0, len(rows))
+	for _, v := range rows {
+		followers = append(followers, Follow{UserID: v.FollowerID, FollowedAt: v.CreatedAt})
+	}
+
+	respondWithJSON(w, http.StatusOK, followers)
+}
+
+func (cfg *apiConfig) handlerListFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.db.ListFollowing(r.Context(), database.ListFollowingParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageLimit:       limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list following error", err)
+		return
+	}
+
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.FolloweeID})
+	}
+
+	following := make([]Follow, 0, len(rows))
+	for _, v := range rows {
+		following = append(following, Follow{UserID: v.FolloweeID, FollowedAt: v.CreatedAt})
+	}
+
+	respondWithJSON(w, http.StatusOK, following)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/entities"
+)
+
+const (
+	defaultTrendingLimit = 10
+	maxTrendingWindow    = 7 * 24 * time.Hour
+)
+
+type TrendingHashtag struct {
+	Tag   string  `json:"tag"`
+	Uses  int64   `json:"uses"`
+	Score float64 `json:"score"`
+}
+
+func (cfg *apiConfig) handlerListHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := entities.FoldTag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid hashtag", nil)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	chrps, err := cfg.db.ListChirpsByHashtag(r.Context(), database.ListChirpsByHashtagParams{
+		Tag:             tag,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageLimit:       limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list hashtag chirps error", err)
+		return
+	}
+
+	if len(chrps) > int(limit) {
+		chrps = chrps[:limit]
+		last := chrps[len(chrps)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	chirpApps, err := cfg.chirpApps(r.Context(), chrps, cfg.optionalUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list hashtag chirps error", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpApps)
+}
+
+// handlerTrendingHashtags ranks tags used within the window by the sum of
+// their uses, each weighted by exp(-ln2 * age / half-life), so a use loses
+// half its weight every half-life.
+func (cfg *apiConfig) handlerTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	window := cfg.trendingWindow
+	if s := r.URL.Query().Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, "window must be a duration between 0 and 168h", err)
+			return
+		}
+		window = d
+	}
+
+	limit := int32(defaultTrendingLimit)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "limit must be a positive integer", err)
+			return
+		}
+		limit = int32(min(n, maxPageLimit))
+	}
+
+	now := time.Now()
+	rows, err := cfg.db.ListTrendingHashtags(r.Context(), database.ListTrendingHashtagsParams{
+		Now:             now,
+		HalfLifeSeconds: cfg.trendingHalfLife.Seconds(),
+		Since:           now.Add(-window),
+		PageLimit:       limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "trending hashtags error", err)
+		return
+	}
+
+	trending := make([]TrendingHashtag, 0, len(rows))
+	for _, v := range rows {
+		trending = append(trending, TrendingHashtag{
+			Tag:   v.Tag,
+			Uses:  v.Uses,
+			Score: v.Score,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, trending)
+}
@@ -2,9 +2,10 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -19,15 +20,14 @@ type TokenType string
 const (
 	// TokenTypeAccess -
 	TokenTypeAccess TokenType = "chirpy-access"
+	// TokenTypeMFAChallenge marks the token that stands in for a login until
+	// the second factor is checked.
+	TokenTypeMFAChallenge TokenType = "chirpy-mfa"
 )
 
-// HashPassword -
+// HashPassword hashes with DefaultPasswordParams.
 func HashPassword(password string) (string, error) {
-	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
-	if err != nil {
-		return "", err
-	}
-	return hash, nil
+	return HashPasswordWithParams(password, DefaultPasswordParams())
 }
 
 // CheckPasswordHash -
@@ -39,48 +39,44 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return match, nil
 }
 
+// AccessClaims are the claims carried by an access token. TokenVersion is
+// compared against the user's current version so tokens can be revoked
+// before they expire.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	TokenVersion int32 `json:"ver"`
+}
+
 // MakeJWT -
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	signingKey := []byte(tokenSecret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
-	return token.SignedString(signingKey)
+	return MakeAccessToken(userID, 0, tokenSecret, expiresIn)
+}
+
+// MakeAccessToken issues an HS256 access token stamped with the user's
+// current token version.
+func MakeAccessToken(userID uuid.UUID, tokenVersion int32, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewHMACKeySet(tokenSecret).MakeAccessToken(userID, tokenVersion, expiresIn)
 }
 
 // ValidateJWT -
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&claimsStruct,
-		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
-	)
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	userIDString, err := token.Claims.GetSubject()
+	claims, err := ParseAccessToken(tokenString, tokenSecret)
 	if err != nil {
 		return uuid.Nil, err
 	}
+	return claims.UserID, nil
+}
 
-	issuer, err := token.Claims.GetIssuer()
-	if err != nil {
-		return uuid.Nil, err
-	}
-	if issuer != string(TokenTypeAccess) {
-		return uuid.Nil, errors.New("invalid issuer")
-	}
+// ParsedAccessToken is a verified access token.
+type ParsedAccessToken struct {
+	UserID       uuid.UUID
+	TokenVersion int32
+}
 
-	id, err := uuid.Parse(userIDString)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
-	}
-	return id, nil
+// ParseAccessToken verifies an HS256 access token and returns its subject
+// and token version.
+func ParseAccessToken(tokenString, tokenSecret string) (ParsedAccessToken, error) {
+	return NewHMACKeySet(tokenSecret).ParseAccessToken(tokenString)
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -102,6 +98,45 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(bte), nil
 }
 
+// RefreshTokenPrefixLen is how many leading characters of a refresh token
+// are kept in the clear so a stored row can be recognised in logs and
+// session lists without revealing the token.
+const RefreshTokenPrefixLen = 8
+
+// HashRefreshToken returns the SHA-256 digest stored in place of a refresh
+// token. Tokens carry 256 random bits, so an unsalted fast hash is enough:
+// there is no dictionary to brute-force.
+func HashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// RefreshTokenPrefix returns the part of token that is stored in the clear.
+func RefreshTokenPrefix(token string) string {
+	if len(token) <= RefreshTokenPrefixLen {
+		return token
+	}
+	return token[:RefreshTokenPrefixLen]
+}
+
+// MakeOneTimeToken returns a random URL-safe token for emailed links such as
+// email verification. Like refresh tokens, only HashOneTimeToken of it is
+// stored.
+func MakeOneTimeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashOneTimeToken returns the SHA-256 digest stored for a one-time token.
+func HashOneTimeToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// GetAPIKey returns the key from an "Authorization: ApiKey <key>" header.
 func GetAPIKey(headers http.Header) (string, error) {
 	bearerToken := headers.Get("Authorization")
 
@@ -109,7 +144,10 @@ func GetAPIKey(headers http.Header) (string, error) {
 		return "", errors.New("API Key token not found")
 	}
 
-	token := strings.TrimPrefix(bearerToken, "ApiKey ")
+	token, ok := strings.CutPrefix(bearerToken, "ApiKey ")
+	if !ok || token == "" {
+		return "", errors.New("malformed authorization header")
+	}
 
 	return token, nil
 }
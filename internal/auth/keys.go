@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Key is one JWT signing or verification key. Asymmetric keys are
+// identified by the kid header; the HMAC key used with SECRETKEY has no kid.
+type Key struct {
+	ID     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+	// retireAt, when set, is when the key stops verifying tokens.
+	retireAt time.Time
+}
+
+// RetireAt returns a copy of k that only verifies tokens until t. It is for
+// keys kept during a migration, which should go away by themselves once the
+// tokens they signed have expired.
+func (k *Key) RetireAt(t time.Time) *Key {
+	retired := *k
+	retired.retireAt = t
+	return &retired
+}
+
+// Algorithm returns the JWS alg the key is used with.
+func (k *Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// NewHMACKey returns an HS256 key. It signs and verifies with the same
+// secret, so it is never published.
+func NewHMACKey(secret string) *Key {
+	return &Key{
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// ParsePrivateKeyPEM parses an RSA (PKCS #1 or PKCS #8) or Ed25519 (PKCS #8)
+// private key. RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+func ParsePrivateKeyPEM(kid string, data []byte) (*Key, error) {
+	if kid == "" {
+		return nil, errors.New("key ID is required")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: kid, method: jwt.SigningMethodRS256, sign: k, verify: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, method: jwt.SigningMethodEdDSA, sign: k, verify: k.Public()}, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", parsed)
+}
+
+// ParsePublicKeyPEM parses an RSA or Ed25519 public key in PKIX form. Such a
+// key can only verify, which is all a retired signing key needs to do while
+// its tokens run out.
+func ParsePublicKeyPEM(kid string, data []byte) (*Key, error) {
+	if kid == "" {
+		return nil, errors.New("key ID is required")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: kid, method: jwt.SigningMethodRS256, verify: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, method: jwt.SigningMethodEdDSA, verify: k}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", parsed)
+}
+
+// ParseKeyPEM accepts either a private or a public key.
+func ParseKeyPEM(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block != nil && block.Type == "PUBLIC KEY" {
+		return ParsePublicKeyPEM(kid, data)
+	}
+	return ParsePrivateKeyPEM(kid, data)
+}
+
+// KeySet signs access tokens with one key and verifies them against any of
+// its keys, so a previous key keeps working during a rotation.
+type KeySet struct {
+	signing *Key
+	verify  map[string]*Key
+}
+
+// NewKeySet builds a set that signs with signing and also accepts tokens
+// signed by any of the extra verification keys.
+func NewKeySet(signing *Key, verify ...*Key) (*KeySet, error) {
+	if signing == nil || signing.sign == nil {
+		return nil, errors.New("signing key must include a private key")
+	}
+	ks := &KeySet{signing: signing, verify: map[string]*Key{signing.ID: signing}}
+	for _, k := range verify {
+		if _, dup := ks.verify[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate key ID %q", k.ID)
+		}
+		ks.verify[k.ID] = k
+	}
+	return ks, nil
+}
+
+// NewHMACKeySet is the HS256 set used when no asymmetric keys are
+// configured.
+func NewHMACKeySet(secret string) *KeySet {
+	ks, _ := NewKeySet(NewHMACKey(secret))
+	return ks
+}
+
+// Sign signs claims with the current signing key, setting the kid header
+// for asymmetric keys.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.sign)
+}
+
+// keyfunc picks the verification key named by the token's kid and refuses
+// tokens whose alg doesn't match that key, which rules out algorithm
+// confusion such as an HS256 token keyed with an RSA public key.
+func (ks *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := ks.verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+	}
+	if !k.retireAt.IsZero() && time.Now().After(k.retireAt) {
+		return nil, fmt.Errorf("key %q is retired", kid)
+	}
+	return k.verify, nil
+}
+
+// MakeAccessToken issues an access token stamped with the user's current
+// token version.
+func (ks *KeySet) MakeAccessToken(userID uuid.UUID, tokenVersion int32, expiresIn time.Duration) (string, error) {
+	return ks.makeToken(TokenTypeAccess, userID, tokenVersion, expiresIn)
+}
+
+// ParseAccessToken verifies an access token and returns its subject and
+// token version. Tokens issued before versions existed carry version 0.
+func (ks *KeySet) ParseAccessToken(tokenString string) (ParsedAccessToken, error) {
+	return ks.parseToken(TokenTypeAccess, tokenString)
+}
+
+// MakeMFAChallenge issues the short-lived token handed out after a correct
+// password when the account also needs a second factor. Its issuer differs
+// from an access token's, so one can never be used as the other.
+func (ks *KeySet) MakeMFAChallenge(userID uuid.UUID, tokenVersion int32, expiresIn time.Duration) (string, error) {
+	return ks.makeToken(TokenTypeMFAChallenge, userID, tokenVersion, expiresIn)
+}
+
+// ParseMFAChallenge verifies a token from MakeMFAChallenge.
+func (ks *KeySet) ParseMFAChallenge(tokenString string) (ParsedAccessToken, error) {
+	return ks.parseToken(TokenTypeMFAChallenge, tokenString)
+}
+
+func (ks *KeySet) makeToken(typ TokenType, userID uuid.UUID, tokenVersion int32, expiresIn time.Duration) (string, error) {
+	return ks.Sign(AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    string(typ),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		TokenVersion: tokenVersion,
+	})
+}
+
+func (ks *KeySet) parseToken(typ TokenType, tokenString string) (ParsedAccessToken, error) {
+	claimsStruct := AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claimsStruct, ks.keyfunc)
+	if err != nil {
+		return ParsedAccessToken{}, err
+	}
+
+	userIDString, err := token.Claims.GetSubject()
+	if err != nil {
+		return ParsedAccessToken{}, err
+	}
+
+	issuer, err := token.Claims.GetIssuer()
+	if err != nil {
+		return ParsedAccessToken{}, err
+	}
+	if issuer != string(typ) {
+		return ParsedAccessToken{}, errors.New("invalid issuer")
+	}
+
+	id, err := uuid.Parse(userIDString)
+	if err != nil {
+		return ParsedAccessToken{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+	return ParsedAccessToken{UserID: id, TokenVersion: claimsStruct.TokenVersion}, nil
+}
+
+// JWK is a public key in RFC 7517 form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every asymmetric key in the set. HMAC
+// keys are secret and never included.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	add := func(k *Key) {
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     k.ID,
+				Use:       "sig",
+				Algorithm: k.Algorithm(),
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     k.ID,
+				Use:       "sig",
+				Algorithm: k.Algorithm(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	// Current signing key first, then the rest in a stable order.
+	add(ks.signing)
+	for _, id := range slices.Sorted(maps.Keys(ks.verify)) {
+		if id != ks.signing.ID {
+			add(ks.verify[id])
+		}
+	}
+	return set
+}
@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func rsaKeyPEM(t *testing.T) (private, public []byte) {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodeKeyPair(t, k, &k.PublicKey)
+}
+
+func ed25519KeyPEM(t *testing.T) (private, public []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodeKeyPair(t, priv, pub)
+}
+
+func encodeKeyPair(t *testing.T, priv, pub any) ([]byte, []byte) {
+	t.Helper()
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+func TestKeySetRoundTrip(t *testing.T) {
+	rsaPriv, _ := rsaKeyPEM(t)
+	edPriv, _ := ed25519KeyPEM(t)
+
+	tests := []struct {
+		name    string
+		pem     []byte
+		wantAlg string
+	}{
+		{name: "RS256", pem: rsaPriv, wantAlg: "RS256"},
+		{name: "EdDSA", pem: edPriv, wantAlg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePrivateKeyPEM("k1", tt.pem)
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyPEM() error = %v", err)
+			}
+			if key.Algorithm() != tt.wantAlg {
+				t.Errorf("Algorithm() = %q, want %q", key.Algorithm(), tt.wantAlg)
+			}
+			ks, err := NewKeySet(key)
+			if err != nil {
+				t.Fatalf("NewKeySet() error = %v", err)
+			}
+
+			userID := uuid.New()
+			token, err := ks.MakeAccessToken(userID, 3, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeAccessToken() error = %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &AccessClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header["kid"] != "k1" || parsed.Header["alg"] != tt.wantAlg {
+				t.Errorf("header = %v, want kid k1 and alg %s", parsed.Header, tt.wantAlg)
+			}
+
+			got, err := ks.ParseAccessToken(token)
+			if err != nil {
+				t.Fatalf("ParseAccessToken() error = %v", err)
+			}
+			if got.UserID != userID || got.TokenVersion != 3 {
+				t.Errorf("ParseAccessToken() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldPriv, oldPub := rsaKeyPEM(t)
+	newPriv, _ := ed25519KeyPEM(t)
+
+	oldKey, err := ParsePrivateKeyPEM("old", oldPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSet, err := NewKeySet(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := oldSet.MakeAccessToken(uuid.New(), 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After rotating, the old key is kept as a public verification key only.
+	newKey, err := ParsePrivateKeyPEM("new", newPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := ParseKeyPEM("old", oldPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewKeySet(newKey, retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotated.ParseAccessToken(oldToken); err != nil {
+		t.Errorf("token signed by retired key rejected: %v", err)
+	}
+	newToken, err := rotated.MakeAccessToken(uuid.New(), 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.ParseAccessToken(newToken); err != nil {
+		t.Errorf("token signed by new key rejected: %v", err)
+	}
+
+	// Once the old key is dropped its tokens stop verifying.
+	trimmed, err := NewKeySet(newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trimmed.ParseAccessToken(oldToken); err == nil {
+		t.Error("token signed by removed key accepted")
+	}
+
+	if _, err := NewKeySet(newKey, newKey); err == nil {
+		t.Error("NewKeySet() accepted duplicate key IDs")
+	}
+	if _, err := NewKeySet(retired); err == nil {
+		t.Error("NewKeySet() accepted a public key for signing")
+	}
+}
+
+func TestKeySetHMACMigration(t *testing.T) {
+	priv, _ := ed25519KeyPEM(t)
+	signing, err := ParsePrivateKeyPEM("signing", priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacToken, err := NewHMACKeySet("secret").MakeAccessToken(uuid.New(), 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		retireAt time.Time
+		wantErr  bool
+	}{
+		{name: "before retirement", retireAt: time.Now().Add(time.Hour), wantErr: false},
+		{name: "after retirement", retireAt: time.Now().Add(-time.Second), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := NewKeySet(signing, NewHMACKey("secret").RetireAt(tt.retireAt))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ks.ParseAccessToken(hmacToken); (err != nil) != tt.wantErr {
+				t.Errorf("ParseAccessToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := ks.JWKS(); len(got.Keys) != 1 {
+				t.Errorf("JWKS() has %d keys, want 1", len(got.Keys))
+			}
+		})
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	_, pub := rsaKeyPEM(t)
+	key, err := ParseKeyPEM("rsa", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, _ := rsaKeyPEM(t)
+	signing, err := ParsePrivateKeyPEM("signing", priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewKeySet(signing, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An attacker who knows the public key signs an HS256 token with it.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    string(TokenTypeAccess),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Subject:   uuid.NewString(),
+		},
+	})
+	token.Header["kid"] = "rsa"
+	forged, err := token.SignedString(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ParseAccessToken(forged); err == nil {
+		t.Error("HS256 token keyed with an RSA public key accepted")
+	}
+
+	// HS256 tokens without a kid are unknown to an asymmetric set.
+	hmacToken, err := MakeAccessToken(uuid.New(), 0, "secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ParseAccessToken(hmacToken); err == nil {
+		t.Error("HS256 token accepted by an asymmetric key set")
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	rsaPriv, _ := rsaKeyPEM(t)
+	_, edPub := ed25519KeyPEM(t)
+
+	signing, err := ParsePrivateKeyPEM("rsa-2026", rsaPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify, err := ParsePublicKeyPEM("ed-2025", edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewKeySet(signing, verify, NewHMACKey("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS() has %d keys, want 2 (HMAC key must not be published)", len(jwks.Keys))
+	}
+
+	rsaJWK, edJWK := jwks.Keys[0], jwks.Keys[1]
+	if rsaJWK.KeyID != "rsa-2026" || rsaJWK.KeyType != "RSA" || rsaJWK.Algorithm != "RS256" || rsaJWK.N == "" || rsaJWK.E != "AQAB" {
+		t.Errorf("RSA JWK = %+v", rsaJWK)
+	}
+	if edJWK.KeyID != "ed-2025" || edJWK.KeyType != "OKP" || edJWK.Curve != "Ed25519" || edJWK.Algorithm != "EdDSA" || len(edJWK.X) != 43 {
+		t.Errorf("Ed25519 JWK = %+v", edJWK)
+	}
+
+	if got := NewHMACKeySet("secret").JWKS(); len(got.Keys) != 0 {
+		t.Errorf("HMAC key set published %d keys", len(got.Keys))
+	}
+}
+
+func TestParseKeyPEMErrors(t *testing.T) {
+	priv, _ := ed25519KeyPEM(t)
+	if _, err := ParsePrivateKeyPEM("", priv); err == nil {
+		t.Error("ParsePrivateKeyPEM() accepted an empty key ID")
+	}
+	if _, err := ParsePrivateKeyPEM("k", []byte("not pem")); err == nil {
+		t.Error("ParsePrivateKeyPEM() accepted non-PEM input")
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})
+	if _, err := ParseKeyPEM("k", cert); err == nil {
+		t.Error("ParseKeyPEM() accepted a certificate block")
+	}
+}
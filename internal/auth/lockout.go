@@ -0,0 +1,32 @@
+package auth
+
+import "time"
+
+// LockoutPolicy turns a count of consecutive failed logins into how long
+// further attempts are refused. The first FreeAttempts failures cost
+// nothing; each one after that doubles the wait, starting at BaseDelay and
+// capped at MaxDelay.
+type LockoutPolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// ResetAfter is how long without a failure before the count starts
+	// over.
+	ResetAfter time.Duration
+}
+
+// Delay returns how long to lock out after the given number of failures.
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := 1; i < over; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(d, p.MaxDelay)
+}
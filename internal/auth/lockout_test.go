@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	p := LockoutPolicy{FreeAttempts: 3, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{3, 0},
+		{4, 30 * time.Second},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{8, 5 * time.Minute},
+		{1000, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := p.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+)
+
+// PasswordParams are the Argon2id costs used for new password hashes. Each
+// hash records the parameters it was made with, so changing them only
+// affects hashes created afterwards.
+type PasswordParams struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+)
+
+// DefaultPasswordParams are 64 MiB, one pass and two lanes. The lane count
+// is fixed rather than taken from the CPU count: it is part of every hash,
+// so a default that varied between hosts would have each one rehash the
+// passwords the others wrote.
+func DefaultPasswordParams() PasswordParams {
+	return PasswordParams{
+		Memory:      64 * 1024,
+		Iterations:  1,
+		Parallelism: 2,
+	}
+}
+
+// Validate rejects parameters Argon2id can't use or that are too weak to be
+// worth using.
+func (p PasswordParams) Validate() error {
+	if p.Iterations < 1 {
+		return errors.New("argon2 iterations must be at least 1")
+	}
+	if p.Parallelism < 1 {
+		return errors.New("argon2 parallelism must be at least 1")
+	}
+	// Argon2 needs at least 8 KiB per lane.
+	if p.Memory < 8*uint32(p.Parallelism) {
+		return fmt.Errorf("argon2 memory must be at least %d KiB for parallelism %d", 8*uint32(p.Parallelism), p.Parallelism)
+	}
+	if p.Memory < 8*1024 {
+		return errors.New("argon2 memory must be at least 8192 KiB")
+	}
+	return nil
+}
+
+func (p PasswordParams) argon2id() *argon2id.Params {
+	return &argon2id.Params{
+		Memory:      p.Memory,
+		Iterations:  p.Iterations,
+		Parallelism: p.Parallelism,
+		SaltLength:  passwordSaltLength,
+		KeyLength:   passwordKeyLength,
+	}
+}
+
+// HashPasswordWithParams hashes password with the given costs.
+func HashPasswordWithParams(password string, params PasswordParams) (string, error) {
+	return argon2id.CreateHash(password, params.argon2id())
+}
+
+// NeedsRehash reports whether hash was made with costs other than params.
+// Call it after a successful CheckPasswordHash, while the plain password is
+// at hand to hash again.
+func NeedsRehash(hash string, params PasswordParams) (bool, error) {
+	got, _, key, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+	return got.Memory != params.Memory ||
+		got.Iterations != params.Iterations ||
+		got.Parallelism != params.Parallelism ||
+		len(key) != passwordKeyLength, nil
+}
+
+var dummyPasswordHashes sync.Map // PasswordParams -> string
+
+// CheckDummyPassword does the same work as CheckPasswordHash against a hash
+// no password matches. Calling it for unknown accounts keeps a login's
+// response time from revealing whether the account exists. The hash is
+// built once per set of params.
+func CheckDummyPassword(password string, params PasswordParams) {
+	hash, ok := dummyPasswordHashes.Load(params)
+	if !ok {
+		h, err := HashPasswordWithParams("chirpy-dummy-password", params)
+		if err != nil {
+			return
+		}
+		hash, _ = dummyPasswordHashes.LoadOrStore(params, h)
+	}
+	CheckPasswordHash(password, hash.(string))
+}
+
+// SuggestPasswordParams times hashing on this host and returns parameters
+// that take roughly target per hash with the given memory and parallelism,
+// along with the measured time. Memory is the main defence against GPU
+// attacks, so pick it first and let the iteration count fill the budget.
+func SuggestPasswordParams(target time.Duration, memory uint32, parallelism uint8) (PasswordParams, time.Duration, error) {
+	params := PasswordParams{Memory: memory, Iterations: 1, Parallelism: parallelism}
+	if err := params.Validate(); err != nil {
+		return PasswordParams{}, 0, err
+	}
+
+	one, err := timePasswordHash(params)
+	if err != nil {
+		return PasswordParams{}, 0, err
+	}
+	if one < target {
+		params.Iterations = uint32(target / one)
+	}
+
+	took, err := timePasswordHash(params)
+	if err != nil {
+		return PasswordParams{}, 0, err
+	}
+	// The estimate assumes time grows linearly with iterations; step back
+	// if it overshot.
+	for took > target && params.Iterations > 1 {
+		params.Iterations--
+		if took, err = timePasswordHash(params); err != nil {
+			return PasswordParams{}, 0, err
+		}
+	}
+	return params, took, nil
+}
+
+// timePasswordHash returns the fastest of three hashes, which is the least
+// disturbed by whatever else the host is doing.
+func timePasswordHash(params PasswordParams) (time.Duration, error) {
+	var best time.Duration
+	for i := range 3 {
+		start := time.Now()
+		if _, err := HashPasswordWithParams("benchmark", params); err != nil {
+			return 0, err
+		}
+		if d := time.Since(start); i == 0 || d < best {
+			best = d
+		}
+	}
+	return best, nil
+}
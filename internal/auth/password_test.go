@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// testPasswordParams keeps hashing fast in tests.
+var testPasswordParams = PasswordParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}
+
+func TestNeedsRehash(t *testing.T) {
+	hash, err := HashPasswordWithParams("s3cret", testPasswordParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		hash    string
+		params  PasswordParams
+		want    bool
+		wantErr bool
+	}{
+		{name: "Same params", hash: hash, params: testPasswordParams, want: false},
+		{name: "More memory", hash: hash, params: PasswordParams{Memory: 16 * 1024, Iterations: 1, Parallelism: 1}, want: true},
+		{name: "More iterations", hash: hash, params: PasswordParams{Memory: 8 * 1024, Iterations: 2, Parallelism: 1}, want: true},
+		{name: "More parallelism", hash: hash, params: PasswordParams{Memory: 8 * 1024, Iterations: 1, Parallelism: 2}, want: true},
+		{name: "Invalid hash", hash: "invalidhash", params: testPasswordParams, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NeedsRehash(tt.hash, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NeedsRehash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A hash made with the new params still verifies and needs no rehash.
+	upgraded := PasswordParams{Memory: 16 * 1024, Iterations: 2, Parallelism: 1}
+	rehashed, err := HashPasswordWithParams("s3cret", upgraded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match, err := CheckPasswordHash("s3cret", rehashed); err != nil || !match {
+		t.Errorf("CheckPasswordHash() on rehashed password = %v, %v", match, err)
+	}
+	if stale, _ := NeedsRehash(rehashed, upgraded); stale {
+		t.Error("NeedsRehash() = true right after rehashing")
+	}
+}
+
+func TestPasswordParamsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  PasswordParams
+		wantErr bool
+	}{
+		{name: "Defaults", params: DefaultPasswordParams()},
+		{name: "Minimum", params: testPasswordParams},
+		{name: "No iterations", params: PasswordParams{Memory: 64 * 1024, Parallelism: 1}, wantErr: true},
+		{name: "No parallelism", params: PasswordParams{Memory: 64 * 1024, Iterations: 1}, wantErr: true},
+		{name: "Too little memory", params: PasswordParams{Memory: 1024, Iterations: 1, Parallelism: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSuggestPasswordParams(t *testing.T) {
+	got, took, err := SuggestPasswordParams(20*time.Millisecond, 8*1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Memory != 8*1024 || got.Parallelism != 1 || got.Iterations < 1 {
+		t.Errorf("SuggestPasswordParams() = %+v", got)
+	}
+	if took <= 0 {
+		t.Errorf("SuggestPasswordParams() measured %v", took)
+	}
+
+	if _, _, err := SuggestPasswordParams(time.Millisecond, 8*1024, 0); err == nil {
+		t.Error("SuggestPasswordParams() accepted zero parallelism")
+	}
+}
+
+func TestCheckDummyPassword(t *testing.T) {
+	// It only has to run without matching anything; the hash is built once
+	// per set of params.
+	CheckDummyPassword("", testPasswordParams)
+	CheckDummyPassword("chirpy", testPasswordParams)
+	hash, ok := dummyPasswordHashes.Load(testPasswordParams)
+	if !ok {
+		t.Fatal("dummy hash was not cached")
+	}
+	if match, err := CheckPasswordHash("chirpy", hash.(string)); err != nil || match {
+		t.Errorf("dummy hash matched or failed to parse: match=%v err=%v", match, err)
+	}
+}
+
+func BenchmarkHashPassword(b *testing.B) {
+	params := DefaultPasswordParams()
+	for b.Loop() {
+		if _, err := HashPasswordWithParams("benchmark", params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
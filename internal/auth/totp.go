@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTPAlgorithm is the HMAC hash used by a TOTP generator.
+type TOTPAlgorithm string
+
+const (
+	TOTPSHA1   TOTPAlgorithm = "SHA1"
+	TOTPSHA256 TOTPAlgorithm = "SHA256"
+	TOTPSHA512 TOTPAlgorithm = "SHA512"
+)
+
+func (a TOTPAlgorithm) hash() func() hash.Hash {
+	switch a {
+	case TOTPSHA256:
+		return sha256.New
+	case TOTPSHA512:
+		return sha512.New
+	}
+	return sha1.New
+}
+
+// totpSecretEncoding is the unpadded base32 that authenticator apps expect.
+var totpSecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP generates and checks RFC 6238 time-based one-time passwords. The
+// zero values of Digits, Period and Algorithm mean 6, 30s and SHA1, which
+// is what every authenticator app supports.
+type TOTP struct {
+	Secret    []byte
+	Digits    int
+	Period    time.Duration
+	Algorithm TOTPAlgorithm
+	// Skew is how many periods either side of now are also accepted, to
+	// allow for clock drift and slow typing.
+	Skew int
+}
+
+func (t TOTP) digits() int {
+	if t.Digits == 0 {
+		return 6
+	}
+	return t.Digits
+}
+
+func (t TOTP) period() time.Duration {
+	if t.Period == 0 {
+		return 30 * time.Second
+	}
+	return t.Period
+}
+
+func (t TOTP) algorithm() TOTPAlgorithm {
+	if t.Algorithm == "" {
+		return TOTPSHA1
+	}
+	return t.Algorithm
+}
+
+// Step returns the RFC 6238 time step counter for at.
+func (t TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.period()/time.Second)
+}
+
+// Code returns the one-time password for at.
+func (t TOTP) Code(at time.Time) string {
+	return t.codeForStep(t.Step(at))
+}
+
+// codeForStep is HOTP (RFC 4226) over the step counter.
+func (t TOTP) codeForStep(step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(t.algorithm().hash(), t.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range t.digits() {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.digits(), bin%mod)
+}
+
+// Verify checks code against the steps around at and returns the step it
+// matched. Callers should store that step and refuse codes for the same or
+// an earlier step, so an observed code can't be replayed.
+func (t TOTP) Verify(code string, at time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != t.digits() {
+		return 0, false
+	}
+	now := t.Step(at)
+	for d := -t.Skew; d <= t.Skew; d++ {
+		step := now + int64(d)
+		if subtle.ConstantTimeCompare([]byte(t.codeForStep(step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI returns the otpauth:// URI that authenticator apps import, usually
+// by scanning it as a QR code.
+func (t TOTP) URI(issuer, account string) string {
+	q := url.Values{}
+	q.Set("secret", EncodeTOTPSecret(t.Secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", string(t.algorithm()))
+	q.Set("digits", fmt.Sprint(t.digits()))
+	q.Set("period", fmt.Sprint(int(t.period()/time.Second)))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// GenerateTOTPSecret returns a random 160-bit secret, the size RFC 4226
+// recommends for HMAC-SHA1.
+func GenerateTOTPSecret() ([]byte, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// EncodeTOTPSecret returns the base32 form of a secret shown to users and
+// stored in the database.
+func EncodeTOTPSecret(secret []byte) string {
+	return totpSecretEncoding.EncodeToString(secret)
+}
+
+// DecodeTOTPSecret parses a base32 secret, ignoring case, spaces and
+// padding.
+func DecodeTOTPSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	s = strings.TrimRight(s, "=")
+	secret, err := totpSecretEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("empty TOTP secret")
+	}
+	return secret, nil
+}
+
+// recoveryCodeEncoding avoids padding; codes are shown in lower case.
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes returns n one-time recovery codes such as
+// "abcd-efgh-ijkl-mnop". Each carries 80 random bits, so storing
+// HashOneTimeToken(NormalizeRecoveryCode(code)) is safe.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, s[0:4]+"-"+s[4:8]+"-"+s[8:12]+"-"+s[12:16])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips the separators and case a user may have
+// typed differently from how the code was shown.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The RFC 6238 appendix B seeds for each hash.
+var (
+	rfcSeedSHA1   = []byte("12345678901234567890")
+	rfcSeedSHA256 = []byte("12345678901234567890123456789012")
+	rfcSeedSHA512 = []byte("1234567890123456789012345678901234567890123456789012345678901234")
+)
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		alg  TOTPAlgorithm
+		want string
+	}{
+		{59, TOTPSHA1, "94287082"},
+		{59, TOTPSHA256, "46119246"},
+		{59, TOTPSHA512, "90693936"},
+		{1111111109, TOTPSHA1, "07081804"},
+		{1111111109, TOTPSHA256, "68084774"},
+		{1111111109, TOTPSHA512, "25091201"},
+		{1111111111, TOTPSHA1, "14050471"},
+		{1111111111, TOTPSHA256, "67062674"},
+		{1111111111, TOTPSHA512, "99943326"},
+		{1234567890, TOTPSHA1, "89005924"},
+		{1234567890, TOTPSHA256, "91819424"},
+		{1234567890, TOTPSHA512, "93441116"},
+		{2000000000, TOTPSHA1, "69279037"},
+		{2000000000, TOTPSHA256, "90698825"},
+		{2000000000, TOTPSHA512, "38618901"},
+		{20000000000, TOTPSHA1, "65353130"},
+		{20000000000, TOTPSHA256, "77737706"},
+		{20000000000, TOTPSHA512, "47863826"},
+	}
+
+	seeds := map[TOTPAlgorithm][]byte{
+		TOTPSHA1:   rfcSeedSHA1,
+		TOTPSHA256: rfcSeedSHA256,
+		TOTPSHA512: rfcSeedSHA512,
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.alg)+"/"+time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			totp := TOTP{Secret: seeds[tt.alg], Digits: 8, Algorithm: tt.alg}
+			if got := totp.Code(time.Unix(tt.unix, 0)); got != tt.want {
+				t.Errorf("Code() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	totp := TOTP{Secret: rfcSeedSHA1, Skew: 1}
+	step := totp.Step(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "Current code", code: totp.Code(now), wantStep: step, wantOK: true},
+		{name: "Previous period", code: totp.Code(now.Add(-30 * time.Second)), wantStep: step - 1, wantOK: true},
+		{name: "Next period", code: totp.Code(now.Add(30 * time.Second)), wantStep: step + 1, wantOK: true},
+		{name: "Outside skew", code: totp.Code(now.Add(-90 * time.Second)), wantOK: false},
+		{name: "Surrounding spaces", code: " " + totp.Code(now) + " ", wantStep: step, wantOK: true},
+		{name: "Wrong length", code: "12345", wantOK: false},
+		{name: "Empty", code: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := totp.Verify(tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("Verify() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && gotStep != tt.wantStep {
+				t.Errorf("Verify() step = %d, want %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+
+	// Without skew only the current period is accepted.
+	strict := TOTP{Secret: rfcSeedSHA1}
+	if _, ok := strict.Verify(totp.Code(now.Add(30*time.Second)), now); ok {
+		t.Error("Verify() without skew accepted the next period's code")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	totp := TOTP{Secret: rfcSeedSHA1}
+	u, err := url.Parse(totp.URI("Chirpy", "alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Chirpy:alice@example.com" {
+		t.Errorf("URI() = %s", u)
+	}
+	q := u.Query()
+	if q.Get("secret") != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" || q.Get("issuer") != "Chirpy" ||
+		q.Get("algorithm") != "SHA1" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("URI() query = %v", q)
+	}
+}
+
+func TestTOTPSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 20 {
+		t.Errorf("GenerateTOTPSecret() returned %d bytes, want 20", len(secret))
+	}
+
+	encoded := EncodeTOTPSecret(secret)
+	for _, in := range []string{encoded, strings.ToLower(encoded), encoded[:8] + " " + encoded[8:]} {
+		got, err := DecodeTOTPSecret(in)
+		if err != nil {
+			t.Fatalf("DecodeTOTPSecret(%q) error = %v", in, err)
+		}
+		if string(got) != string(secret) {
+			t.Errorf("DecodeTOTPSecret(%q) did not round-trip", in)
+		}
+	}
+
+	if _, err := DecodeTOTPSecret("not base32!"); err == nil {
+		t.Error("DecodeTOTPSecret() accepted invalid input")
+	}
+	if _, err := DecodeTOTPSecret(""); err == nil {
+		t.Error("DecodeTOTPSecret() accepted an empty secret")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("GenerateRecoveryCodes() returned %d codes, want 10", len(codes))
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 19 || strings.Count(c, "-") != 3 {
+			t.Errorf("code %q is not in xxxx-xxxx-xxxx-xxxx form", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+
+		typed := strings.ToUpper(strings.ReplaceAll(c, "-", " "))
+		if NormalizeRecoveryCode(typed) != NormalizeRecoveryCode(c) {
+			t.Errorf("NormalizeRecoveryCode(%q) != NormalizeRecoveryCode(%q)", typed, c)
+		}
+	}
+}
+
+func TestMFAChallengeIsNotAnAccessToken(t *testing.T) {
+	ks := NewHMACKeySet("secret")
+	userID := uuid.New()
+
+	challenge, err := ks.MakeMFAChallenge(userID, 2, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ks.ParseMFAChallenge(challenge)
+	if err != nil {
+		t.Fatalf("ParseMFAChallenge() error = %v", err)
+	}
+	if got.UserID != userID || got.TokenVersion != 2 {
+		t.Errorf("ParseMFAChallenge() = %+v", got)
+	}
+	if _, err := ks.ParseAccessToken(challenge); err == nil {
+		t.Error("ParseAccessToken() accepted an MFA challenge")
+	}
+
+	access, err := ks.MakeAccessToken(userID, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ParseMFAChallenge(access); err == nil {
+		t.Error("ParseMFAChallenge() accepted an access token")
+	}
+}
@@ -10,15 +10,112 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS(
+    SELECT 1 FROM chirps WHERE reply_to_id = $1::uuid
+)::boolean AS has_replies
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, id)
+	var has_replies bool
+	err := row.Scan(&has_replies)
+	return has_replies, err
+}
+
+const countRepliesForChirps = `-- name: CountRepliesForChirps :many
+SELECT reply_to_id::uuid AS chirp_id, COUNT(*) AS reply_count
+FROM chirps
+WHERE reply_to_id = ANY($1::uuid[])
+  AND deleted_at IS NULL
+GROUP BY reply_to_id
+`
+
+type CountRepliesForChirpsRow struct {
+	ChirpID    uuid.UUID
+	ReplyCount int64
+}
+
+func (q *Queries) CountRepliesForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]CountRepliesForChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRepliesForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRepliesForChirpsRow
+	for rows.Next() {
+		var i CountRepliesForChirpsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countRepostsForChirps = `-- name: CountRepostsForChirps :many
+SELECT
+    COALESCE(rechirp_of, quote_of)::uuid AS chirp_id,
+    COUNT(*) FILTER (WHERE kind = 'rechirp') AS rechirp_count,
+    COUNT(*) FILTER (WHERE kind = 'quote') AS quote_count
+FROM chirps
+WHERE (rechirp_of = ANY($1::uuid[]) OR quote_of = ANY($1::uuid[]))
+  AND deleted_at IS NULL
+GROUP BY COALESCE(rechirp_of, quote_of)
+`
+
+type CountRepostsForChirpsRow struct {
+	ChirpID      uuid.UUID
+	RechirpCount int64
+	QuoteCount   int64
+}
+
+func (q *Queries) CountRepostsForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]CountRepostsForChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRepostsForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRepostsForChirpsRow
+	for rows.Next() {
+		var i CountRepostsForChirpsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
 INSERT INTO chirps(
-    id, created_at, updated_at, body, user_id
+    id, created_at, updated_at, body, user_id, reply_to_id, kind, quote_of
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6, $7, $8
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of
 `
 
 type CreateChirpParams struct {
@@ -27,6 +124,9 @@ type CreateChirpParams struct {
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	ReplyToID uuid.NullUUID
+	Kind      string
+	QuoteOf   uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
@@ -36,6 +136,53 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		arg.UpdatedAt,
 		arg.Body,
 		arg.UserID,
+		arg.ReplyToID,
+		arg.Kind,
+		arg.QuoteOf,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodyTsv,
+		&i.EditedAt,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps(
+    id, created_at, updated_at, body, user_id, kind, rechirp_of
+) VALUES (
+    $1, $2, $3, '', $4, 'rechirp', $5
+)
+ON CONFLICT (user_id, rechirp_of) WHERE kind = 'rechirp' DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of
+`
+
+type CreateRechirpParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.RechirpOf,
 	)
 	var i Chirp
 	err := row.Scan(
@@ -44,6 +191,13 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.BodyTsv,
+		&i.EditedAt,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
 	)
 	return i, err
 }
@@ -58,8 +212,63 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :one
+DELETE FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2 AND kind = 'rechirp'
+RETURNING id
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :many
+DELETE FROM chirps
+WHERE rechirp_of = $1 AND kind = 'rechirp'
+RETURNING id, user_id
+`
+
+type DeleteRechirpsOfRow struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, rechirpOf uuid.NullUUID) ([]DeleteRechirpsOfRow, error) {
+	rows, err := q.db.QueryContext(ctx, deleteRechirpsOf, rechirpOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeleteRechirpsOfRow
+	for rows.Next() {
+		var i DeleteRechirpsOfRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
 WHERE id=$1
 `
 
@@ -72,16 +281,247 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.BodyTsv,
+		&i.EditedAt,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const getChirpByIDForUpdate = `-- name: GetChirpByIDForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE id=$1
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpByIDForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIDForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodyTsv,
+		&i.EditedAt,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
 	)
 	return i, err
 }
 
-const listChirps = `-- name: ListChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+const listChirpAncestors = `-- name: ListChirpAncestors :many
+WITH RECURSIVE ancestors(id, reply_to_id, depth) AS (
+    SELECT p.id, p.reply_to_id, 1
+    FROM chirps p
+    JOIN chirps child ON child.reply_to_id = p.id
+    WHERE child.id = $1
+    UNION ALL
+    SELECT p.id, p.reply_to_id, a.depth + 1
+    FROM chirps p
+    JOIN ancestors a ON p.id = a.reply_to_id
+    WHERE a.depth < $2::integer
+)
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.body_tsv, c.edited_at, c.reply_to_id, c.deleted_at, c.kind, c.rechirp_of, c.quote_of, a.depth::integer AS depth
+FROM ancestors a
+JOIN chirps c ON c.id = a.id
+ORDER BY a.depth DESC
+`
+
+type ListChirpAncestorsParams struct {
+	ID       uuid.UUID
+	MaxDepth int32
+}
+
+type ListChirpAncestorsRow struct {
+	Chirp Chirp
+	Depth int32
+}
+
+func (q *Queries) ListChirpAncestors(ctx context.Context, arg ListChirpAncestorsParams) ([]ListChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpAncestors, arg.ID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpAncestorsRow
+	for rows.Next() {
+		var i ListChirpAncestorsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.BodyTsv,
+			&i.Chirp.EditedAt,
+			&i.Chirp.ReplyToID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.Kind,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpDescendants = `-- name: ListChirpDescendants :many
+WITH RECURSIVE descendants(id, depth) AS (
+    SELECT r.id, 1
+    FROM chirps r
+    WHERE r.reply_to_id = $1::uuid
+    UNION ALL
+    SELECT r.id, d.depth + 1
+    FROM chirps r
+    JOIN descendants d ON r.reply_to_id = d.id
+    WHERE d.depth < $2::integer
+)
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.body_tsv, c.edited_at, c.reply_to_id, c.deleted_at, c.kind, c.rechirp_of, c.quote_of, d.depth::integer AS depth
+FROM descendants d
+JOIN chirps c ON c.id = d.id
+ORDER BY d.depth ASC, c.created_at ASC, c.id ASC
+LIMIT $3
+`
+
+type ListChirpDescendantsParams struct {
+	ID       uuid.UUID
+	MaxDepth int32
+	MaxRows  int32
+}
+
+type ListChirpDescendantsRow struct {
+	Chirp Chirp
+	Depth int32
+}
+
+func (q *Queries) ListChirpDescendants(ctx context.Context, arg ListChirpDescendantsParams) ([]ListChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpDescendants, arg.ID, arg.MaxDepth, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpDescendantsRow
+	for rows.Next() {
+		var i ListChirpDescendantsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.BodyTsv,
+			&i.Chirp.EditedAt,
+			&i.Chirp.ReplyToID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.Kind,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE (created_at, id) > ($1::timestamp, $2::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
+LIMIT $3
+`
+
+type ListChirpsAscParams struct {
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	PageLimit      int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc, arg.AfterCreatedAt, arg.AfterID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByAuthorAsc = `-- name: ListChirpsByAuthorAsc :many
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE user_id = $1
+  AND (created_at, id) > ($2::timestamp, $3::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
+LIMIT $4
 `
 
-func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, listChirps)
+type ListChirpsByAuthorAscParams struct {
+	UserID         uuid.UUID
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	PageLimit      int32
+}
+
+func (q *Queries) ListChirpsByAuthorAsc(ctx context.Context, arg ListChirpsByAuthorAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByAuthorAsc,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +535,13 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +555,461 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const listChirpsByAuthorDesc = `-- name: ListChirpsByAuthorDesc :many
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE user_id = $1
+  AND (created_at, id) < ($2::timestamp, $3::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListChirpsByAuthorDescParams struct {
+	UserID          uuid.UUID
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) ListChirpsByAuthorDesc(ctx context.Context, arg ListChirpsByAuthorDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByAuthorDesc,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByHashtag = `-- name: ListChirpsByHashtag :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.body_tsv, c.edited_at, c.reply_to_id, c.deleted_at, c.kind, c.rechirp_of, c.quote_of FROM chirps c
+JOIN chirp_hashtags h ON h.chirp_id = c.id
+WHERE h.tag = $1
+  AND (h.created_at, h.chirp_id) < ($2::timestamp, $3::uuid)
+  AND c.deleted_at IS NULL
+ORDER BY h.created_at DESC, h.chirp_id DESC
+LIMIT $4
+`
+
+type ListChirpsByHashtagParams struct {
+	Tag             string
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) ListChirpsByHashtag(ctx context.Context, arg ListChirpsByHashtagParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByHashtag,
+		arg.Tag,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByIDs = `-- name: ListChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE (created_at, id) < ($1::timestamp, $2::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+type ListChirpsDescParams struct {
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc, arg.BeforeCreatedAt, arg.BeforeID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsMentioningUser = `-- name: ListChirpsMentioningUser :many
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE id IN (
+    SELECT chirp_id FROM chirp_mentions WHERE chirp_mentions.user_id = $1
+)
+  AND (created_at, id) < ($2::timestamp, $3::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListChirpsMentioningUserParams struct {
+	UserID          uuid.UUID
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) ListChirpsMentioningUser(ctx context.Context, arg ListChirpsMentioningUserParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsMentioningUser,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeline = `-- name: ListTimeline :many
+SELECT id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of FROM chirps
+WHERE user_id IN (
+    SELECT followee_id FROM follows WHERE follower_id = $1
+)
+  AND (created_at, id) < ($2::timestamp, $3::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListTimelineParams struct {
+	UserID          uuid.UUID
+	BeforeCreatedAt time.Time
+	BeforeID        uuid.UUID
+	PageLimit       int32
+}
+
+func (q *Queries) ListTimeline(ctx context.Context, arg ListTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeline,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.BodyTsv,
+			&i.EditedAt,
+			&i.ReplyToID,
+			&i.DeletedAt,
+			&i.Kind,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT
+    c.id, c.created_at, c.updated_at, c.body, c.user_id, c.body_tsv, c.edited_at, c.reply_to_id, c.deleted_at, c.kind, c.rechirp_of, c.quote_of,
+    ts_rank_cd(c.body_tsv, query)::real AS rank,
+    ts_headline('english', c.body, query, $1::text) AS headline
+FROM chirps c, websearch_to_tsquery('english', $2::text) query
+WHERE c.body_tsv @@ query
+  AND c.deleted_at IS NULL
+  AND ($3::uuid IS NULL OR c.user_id = $3::uuid)
+ORDER BY
+    CASE WHEN $4::text = 'asc' THEN c.created_at END ASC,
+    CASE WHEN $4::text = 'desc' THEN c.created_at END DESC,
+    rank DESC,
+    c.id ASC
+LIMIT $5
+`
+
+type SearchChirpsParams struct {
+	HeadlineOptions string
+	Query           string
+	AuthorID        uuid.NullUUID
+	SortOrder       string
+	PageLimit       int32
+}
+
+type SearchChirpsRow struct {
+	Chirp    Chirp
+	Rank     float32
+	Headline string
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.HeadlineOptions,
+		arg.Query,
+		arg.AuthorID,
+		arg.SortOrder,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.BodyTsv,
+			&i.Chirp.EditedAt,
+			&i.Chirp.ReplyToID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.Kind,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Rank,
+			&i.Headline,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :one
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, tombstoneChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodyTsv,
+		&i.EditedAt,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW(), edited_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, body_tsv, edited_at, reply_to_id, deleted_at, kind, rechirp_of, quote_of
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.BodyTsv,
+		&i.EditedAt,
+		&i.ReplyToID,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
@@ -11,28 +11,219 @@ import (
 	"github.com/google/uuid"
 )
 
+type ChirpFlag struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Reasons    []string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+	CreatedAt   time.Time
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	BodyTsv   interface{}
+	EditedAt  sql.NullTime
+	ReplyToID uuid.NullUUID
+	DeletedAt sql.NullTime
+	Kind      string
+	RechirpOf uuid.NullUUID
+	QuoteOf   uuid.NullUUID
 }
 
-type RefreshToken struct {
-	Token     string
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LockoutEvent struct {
+	ID          uuid.UUID
+	Scope       string
+	Key         string
+	Failures    int32
+	LockedUntil time.Time
+	IpAddress   string
+	UserAgent   string
+	CreatedAt   time.Time
+}
+
+type LoginFailure struct {
+	Scope        string
+	Key          string
+	Failures     int32
+	LastFailedAt time.Time
+	LockedUntil  sql.NullTime
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  []byte
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type ModerationWord struct {
+	Word      string
+	Action    string
+	CreatedAt time.Time
+}
+
+type OutboxEvent struct {
+	ID           uuid.UUID
+	EventType    string
+	Payload      []byte
+	CreatedAt    time.Time
+	DispatchedAt sql.NullTime
+}
+
+type RefreshToken struct {
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	UserID           uuid.UUID
+	ExpiresAt        time.Time
+	RevokedAt        sql.NullTime
+	FamilyID         uuid.UUID
+	ID               uuid.UUID
+	TokenHash        []byte
+	TokenPrefix      string
+	SessionStartedAt time.Time
+	LastUsedAt       time.Time
+	UserAgent        string
+	IpAddress        string
+}
+
+type ScheduledChirp struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Body          string
+	PublishAt     time.Time
+	Status        string
+	ChirpID       uuid.NullUUID
+	LastError     sql.NullString
+	CreatedAt     time.Time
+	Attempts      int32
+	NextAttemptAt time.Time
+}
+
+type SubscriptionEvent struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	EventKey   sql.NullString
+	Event      string
+	Payload    string
+	OccurredAt time.Time
+	Applied    bool
+	CreatedAt  time.Time
+}
+
+type Subscription struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	Plan               string
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   sql.NullTime
+	LastEventAt        time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type UserToken struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	Purpose   string
+	TokenHash []byte
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	Username        sql.NullString
+	TokenVersion    int32
+	EmailVerifiedAt sql.NullTime
+	TotpSecret      sql.NullString
+	TotpEnabledAt   sql.NullTime
+	TotpLastStep    int64
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	EndpointID    uuid.UUID
+	EventID       uuid.UUID
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	DeliveredAt   sql.NullTime
+	CreatedAt     time.Time
+}
+
+type WebhookDeliveryAttempt struct {
+	ID           uuid.UUID
+	DeliveryID   uuid.UUID
+	Attempt      int32
+	StatusCode   sql.NullInt32
+	Error        sql.NullString
+	ResponseBody string
+	DurationMs   int32
+	AttemptedAt  time.Time
+}
+
+type WebhookEndpoint struct {
+	ID         uuid.UUID
+	Url        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type WebhookEvent struct {
+	ID            uuid.UUID
+	Source        string
+	DeliveryID    sql.NullString
+	Payload       []byte
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	ReceivedAt    time.Time
+	ProcessedAt   sql.NullTime
 }
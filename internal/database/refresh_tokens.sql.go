@@ -13,80 +13,263 @@ import (
 	"github.com/google/uuid"
 )
 
+const consumeRefreshToken = `-- name: ConsumeRefreshToken :one
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+RETURNING created_at, updated_at, user_id, expires_at, revoked_at, family_id, id, token_hash, token_prefix, session_started_at, last_used_at, user_agent, ip_address
+`
+
+func (q *Queries) ConsumeRefreshToken(ctx context.Context, tokenHash []byte) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, consumeRefreshToken, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ID,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.SessionStartedAt,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+	)
+	return i, err
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens(
-    token,
+    id,
+    token_hash,
+    token_prefix,
     created_at,
     updated_at,
     user_id,
     expires_at,
-    revoked_at
+    revoked_at,
+    family_id,
+    session_started_at,
+    last_used_at,
+    user_agent,
+    ip_address
 ) VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
-) RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
+) RETURNING created_at, updated_at, user_id, expires_at, revoked_at, family_id, id, token_hash, token_prefix, session_started_at, last_used_at, user_agent, ip_address
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	ID               uuid.UUID
+	TokenHash        []byte
+	TokenPrefix      string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	UserID           uuid.UUID
+	ExpiresAt        time.Time
+	RevokedAt        sql.NullTime
+	FamilyID         uuid.UUID
+	SessionStartedAt time.Time
+	LastUsedAt       time.Time
+	UserAgent        string
+	IpAddress        string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
 	row := q.db.QueryRowContext(ctx, createRefreshToken,
-		arg.Token,
+		arg.ID,
+		arg.TokenHash,
+		arg.TokenPrefix,
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.ExpiresAt,
 		arg.RevokedAt,
+		arg.FamilyID,
+		arg.SessionStartedAt,
+		arg.LastUsedAt,
+		arg.UserAgent,
+		arg.IpAddress,
 	)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ID,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.SessionStartedAt,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+	)
+	return i, err
+}
+
+const getRefreshToken = `-- name: GetRefreshToken :one
+SELECT created_at, updated_at, user_id, expires_at, revoked_at, family_id, id, token_hash, token_prefix, session_started_at, last_used_at, user_agent, ip_address FROM refresh_tokens
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetRefreshToken(ctx context.Context, tokenHash []byte) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshToken, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ID,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.SessionStartedAt,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens 
-WHERE token = $1 AND revoked_at IS NULL
+SELECT created_at, updated_at, user_id, expires_at, revoked_at, family_id, id, token_hash, token_prefix, session_started_at, last_used_at, user_agent, ip_address FROM refresh_tokens 
+WHERE token_hash = $1 AND revoked_at IS NULL
 `
 
-func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
+func (q *Queries) GetUserFromRefreshToken(ctx context.Context, tokenHash []byte) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, tokenHash)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ID,
+		&i.TokenHash,
+		&i.TokenPrefix,
+		&i.SessionStartedAt,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
 	)
 	return i, err
 }
 
-const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT created_at, updated_at, user_id, expires_at, revoked_at, family_id, id, token_hash, token_prefix, session_started_at, last_used_at, user_agent, ip_address FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY last_used_at DESC, family_id
+`
+
+func (q *Queries) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.FamilyID,
+			&i.ID,
+			&i.TokenHash,
+			&i.TokenPrefix,
+			&i.SessionStartedAt,
+			&i.LastUsedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAllUserRefreshTokens = `-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllUserRefreshTokens, userID)
+	return err
+}
+
+const revokeRefreshToken = `-- name: RevokeRefreshToken :one
 UPDATE refresh_tokens 
 SET updated_at = Now(), revoked_at=Now()
-WHERE token=$1
+WHERE token_hash=$1
+RETURNING user_id
+`
+
+func (q *Queries) RevokeRefreshToken(ctx context.Context, tokenHash []byte) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, revokeRefreshToken, tokenHash)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
 	return err
 }
+
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeUserSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,25 +7,62 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const advanceUserTOTPStep = `-- name: AdvanceUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_last_step < $2
+`
+
+type AdvanceUserTOTPStepParams struct {
+	ID           uuid.UUID
+	TotpLastStep int64
+}
+
+func (q *Queries) AdvanceUserTOTPStep(ctx context.Context, arg AdvanceUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, advanceUserTOTPStep, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const bumpUserTokenVersion = `-- name: BumpUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1, updated_at = NOW()
+WHERE id = $1
+RETURNING token_version
+`
+
+func (q *Queries) BumpUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, bumpUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users(
     id,
     created_at,
     updated_at,
     email,
-    hashed_password
+    hashed_password,
+    username
 ) VALUES (
     $1,
     $2,
     $3,
     $4, 
-    $5
-) RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+    $5,
+    $6
+) RETURNING id, created_at, updated_at, email, hashed_password, username, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_step
 `
 
 type CreateUserParams struct {
@@ -34,6 +71,7 @@ type CreateUserParams struct {
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -43,6 +81,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.UpdatedAt,
 		arg.Email,
 		arg.HashedPassword,
+		arg.Username,
 	)
 	var i User
 	err := row.Scan(
@@ -51,7 +90,12 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
@@ -65,8 +109,38 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, id)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :execrows
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+`
+
+type EnableUserTOTPParams struct {
+	ID           uuid.UUID
+	TotpLastStep int64
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableUserTOTP, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red from users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, username, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_step from users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -78,13 +152,18 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE id=$1
+SELECT id, created_at, updated_at, email, hashed_password, username, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id=$1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -96,26 +175,101 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :one
-UPDATE users 
-SET email = $2, hashed_password = $3, updated_at = now()
-WHERE id=$1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+const getUserByIDForUpdate = `-- name: GetUserByIDForUpdate :one
+SELECT id, created_at, updated_at, email, hashed_password, username, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_step FROM users
+WHERE id = $1
+FOR UPDATE
 `
 
-type UpdateUserParams struct {
-	ID             uuid.UUID
-	Email          string
-	HashedPassword string
+func (q *Queries) GetUserByIDForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIDForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Username,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword)
+const getUserTokenVersion = `-- name: GetUserTokenVersion :one
+SELECT token_version FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
+const listUsersByUsernames = `-- name: ListUsersByUsernames :many
+SELECT id, created_at, updated_at, email, hashed_password, username, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_step FROM users
+WHERE lower(username) = ANY($1::text[])
+`
+
+func (q *Queries) ListUsersByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByUsernames, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.Username,
+			&i.TokenVersion,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, username, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_step
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, markEmailVerified, id)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -123,20 +277,80 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
-const updateUserChirpyRed = `-- name: UpdateUserChirpyRed :one
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE users
+SET hashed_password = $1
+WHERE id = $2 AND hashed_password = $3
+`
+
+type RehashUserPasswordParams struct {
+	NewHash string
+	ID      uuid.UUID
+	OldHash string
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rehashUserPassword, arg.NewHash, arg.ID, arg.OldHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :execrows
 UPDATE users
-set is_chirpy_red=true, updated_at=NOW()
-WHERE id=$1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SET totp_secret = $2, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1 AND totp_enabled_at IS NULL
+`
+
+type SetUserTOTPSecretParams struct {
+	ID         uuid.UUID
+	TotpSecret sql.NullString
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserTOTPSecret, arg.ID, arg.TotpSecret)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users 
+SET email_verified_at = CASE WHEN email = $1 THEN email_verified_at END,
+    email = $1,
+    hashed_password = $2,
+    username = COALESCE($3, username),
+    updated_at = now()
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, hashed_password, username, token_version, email_verified_at, totp_secret, totp_enabled_at, totp_last_step
 `
 
-func (q *Queries) UpdateUserChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUserChirpyRed, id)
+type UpdateUserParams struct {
+	Email          string
+	HashedPassword string
+	Username       sql.NullString
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Username,
+		arg.ID,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -144,7 +358,28 @@ func (q *Queries) UpdateUserChirpyRed(ctx context.Context, id uuid.UUID) (User,
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Username,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
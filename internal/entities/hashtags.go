@@ -0,0 +1,90 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTagLength caps the length of a stored hashtag in bytes.
+const MaxTagLength = 100
+
+// Hashtag is a #tag found in a chirp body. Start and End are byte offsets
+// into the body and include the leading '#'.
+type Hashtag struct {
+	Tag   string
+	Start int
+	End   int
+}
+
+// Hashtags returns the hashtags in body in order of appearance. A tag starts
+// with '#' at the beginning of the text or after a non-word character and
+// runs over letters, digits, marks and underscores. Tags made only of digits
+// ("#1") are ignored. Tag is case folded, so "#Go" and "#GO" match.
+func Hashtags(body string) []Hashtag {
+	var tags []Hashtag
+	for i := 0; i < len(body); {
+		if body[i] != '#' || (i > 0 && isWordRune(lastRune(body[:i]))) {
+			_, size := utf8.DecodeRuneInString(body[i:])
+			i += size
+			continue
+		}
+
+		end := i + 1
+		hasLetter := false
+		for end < len(body) {
+			r, size := utf8.DecodeRuneInString(body[end:])
+			if !isWordRune(r) {
+				break
+			}
+			if unicode.IsLetter(r) {
+				hasLetter = true
+			}
+			end += size
+		}
+
+		if hasLetter && end-(i+1) <= MaxTagLength {
+			tags = append(tags, Hashtag{
+				Tag:   FoldTag(body[i+1 : end]),
+				Start: i,
+				End:   end,
+			})
+		}
+		i = end
+	}
+	return tags
+}
+
+// FoldTag maps a tag to its case-folded form so that every spelling that
+// differs only by case compares equal, including runes such as the Kelvin
+// sign or the Greek final sigma that strings.ToLower leaves alone.
+func FoldTag(tag string) string {
+	tag = strings.TrimPrefix(tag, "#")
+	var b strings.Builder
+	b.Grow(len(tag))
+	for _, r := range tag {
+		b.WriteRune(foldRune(r))
+	}
+	return b.String()
+}
+
+// foldRune picks the smallest rune in r's case-folding orbit and lowercases
+// it, giving one representative per orbit.
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return unicode.ToLower(min)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Hashtag
+	}{
+		{
+			name: "Single tag",
+			body: "hello #golang",
+			want: []Hashtag{{Tag: "golang", Start: 6, End: 13}},
+		},
+		{
+			name: "Trailing punctuation is not part of the tag",
+			body: "#Go, #rust!",
+			want: []Hashtag{{Tag: "go", Start: 0, End: 3}, {Tag: "rust", Start: 5, End: 10}},
+		},
+		{
+			name: "Mid-word hash is ignored",
+			body: "issue#42 and c#sharp",
+			want: nil,
+		},
+		{
+			name: "Digits only is ignored",
+			body: "we're #1",
+			want: nil,
+		},
+		{
+			name: "Unicode tag",
+			body: "café #Größe",
+			want: []Hashtag{{Tag: "größe", Start: 6, End: 14}},
+		},
+		{
+			name: "Empty tag",
+			body: "# nothing",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want string
+	}{
+		{name: "Leading hash is dropped", tag: "#GoLang", want: "golang"},
+		{name: "Kelvin sign", tag: "\u212Aelvin", want: "kelvin"},
+		{name: "Long s", tag: "\u017Fun", want: "sun"},
+		{name: "Capital sigma", tag: "ΟΔΟΣ", want: "οδοσ"},
+		{name: "Final sigma", tag: "οδος", want: "οδοσ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FoldTag(tt.tag); got != tt.want {
+				t.Errorf("FoldTag(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
+// Mention is an @username found in a chirp body. Start and End are byte
+// offsets into the body and include the leading '@'. Username is as written;
+// use strings.ToLower to match it against stored usernames.
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// Mentions returns the @mentions in body in order of appearance. An '@'
+// preceded by a word character (as in an email address) does not start a
+// mention, and only spellings that are valid usernames are returned.
+func Mentions(body string) []Mention {
+	var mentions []Mention
+	for i := 0; i < len(body); {
+		if body[i] != '@' || (i > 0 && isWordRune(lastRune(body[:i]))) {
+			_, size := utf8.DecodeRuneInString(body[i:])
+			i += size
+			continue
+		}
+
+		end := i + 1
+		for end < len(body) && isUsernameByte(body[end]) {
+			end++
+		}
+
+		if ValidUsername(body[i+1 : end]) {
+			mentions = append(mentions, Mention{
+				Username: body[i+1 : end],
+				Start:    i,
+				End:      end,
+			})
+		}
+		i = end
+	}
+	return mentions
+}
+
+// ValidUsername reports whether s is 3 to 30 ASCII letters, digits or
+// underscores.
+func ValidUsername(s string) bool {
+	if len(s) < MinUsernameLength || len(s) > MaxUsernameLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isUsernameByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeUsername returns the form usernames are compared in.
+func NormalizeUsername(s string) string {
+	return strings.ToLower(strings.TrimPrefix(s, "@"))
+}
+
+func isUsernameByte(b byte) bool {
+	return b == '_' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Mention
+	}{
+		{
+			name: "Single mention",
+			body: "hi @alice",
+			want: []Mention{{Username: "alice", Start: 3, End: 9}},
+		},
+		{
+			name: "Punctuation ends the mention",
+			body: "@Bob_1, meet @carol!",
+			want: []Mention{{Username: "Bob_1", Start: 0, End: 6}, {Username: "carol", Start: 13, End: 19}},
+		},
+		{
+			name: "Email address is not a mention",
+			body: "mail me at dave@example.com",
+			want: nil,
+		},
+		{
+			name: "Too short",
+			body: "@ab",
+			want: nil,
+		},
+		{
+			name: "Offsets are in bytes",
+			body: "héllo @eve",
+			want: []Mention{{Username: "eve", Start: 7, End: 11}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"alice", true},
+		{"Bob_1", true},
+		{"ab", false},
+		{"this_username_is_far_too_long_1", false},
+		{"with space", false},
+		{"émile", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidUsername(tt.username); got != tt.want {
+			t.Errorf("ValidUsername(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+}
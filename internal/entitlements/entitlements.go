@@ -0,0 +1,155 @@
+// Package entitlements defines what each plan lets a user do. Plans and
+// their limits come from configuration, so changing what Chirpy Red
+// includes doesn't need a code change.
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// PlanFree is the plan of users without an active subscription. Every
+// configuration defines it, and plans that map to nothing else fall back
+// to it.
+const PlanFree = "free"
+
+// Feature is something a plan either includes or doesn't.
+type Feature string
+
+const (
+	FeatureEditChirps      Feature = "edit_chirps"
+	FeatureScheduledChirps Feature = "scheduled_chirps"
+)
+
+// Entitlements are one plan's limits.
+type Entitlements struct {
+	// MaxChirpLength is the longest chirp body allowed, in bytes.
+	MaxChirpLength int `json:"max_chirp_length"`
+	// EditChirps allows editing chirps after posting.
+	EditChirps bool `json:"edit_chirps"`
+	// MaxScheduledChirps is how many chirps may wait to be published at
+	// once. Zero means the plan can't schedule chirps.
+	MaxScheduledChirps int `json:"max_scheduled_chirps"`
+	// ChirpsPerHour limits how fast chirps are posted, scheduled or
+	// rechirped. Zero means no limit.
+	ChirpsPerHour int `json:"chirps_per_hour"`
+}
+
+// Has reports whether the entitlements include f.
+func (e Entitlements) Has(f Feature) bool {
+	switch f {
+	case FeatureEditChirps:
+		return e.EditChirps
+	case FeatureScheduledChirps:
+		return e.MaxScheduledChirps > 0
+	}
+	return false
+}
+
+// Config maps plan names to their entitlements. Billing may know a plan
+// by more names than are worth configuring, such as "red_yearly" for
+// "red": Aliases maps those names to a configured plan, and PaidFallback,
+// when set, is the plan for any other paid plan.
+type Config struct {
+	Plans        map[string]Entitlements `json:"plans"`
+	Aliases      map[string]string       `json:"aliases,omitempty"`
+	PaidFallback string                  `json:"paid_fallback,omitempty"`
+}
+
+// Default is used when no configuration file is given.
+func Default() *Config {
+	return &Config{Plans: map[string]Entitlements{
+		PlanFree: {
+			MaxChirpLength: 140,
+			ChirpsPerHour:  30,
+		},
+		"red": {
+			MaxChirpLength:     1000,
+			EditChirps:         true,
+			MaxScheduledChirps: 100,
+			ChirpsPerHour:      300,
+		},
+	}, PaidFallback: "red"}
+}
+
+// Resolve returns the configured plan that plan maps to: plan itself, its
+// alias, or PaidFallback. When none applies it returns the free plan and
+// false, so callers can report a paid plan that lost its entitlements.
+func (c *Config) Resolve(plan string) (string, bool) {
+	if _, ok := c.Plans[plan]; ok {
+		return plan, true
+	}
+	if alias, ok := c.Aliases[plan]; ok {
+		return alias, true
+	}
+	if c.PaidFallback != "" && plan != "" {
+		return c.PaidFallback, true
+	}
+	return PlanFree, false
+}
+
+// For returns the entitlements of the plan that plan resolves to.
+func (c *Config) For(plan string) Entitlements {
+	name, _ := c.Resolve(plan)
+	return c.Plans[name]
+}
+
+// Validate checks that the free plan exists and every limit makes sense.
+func (c *Config) Validate() error {
+	if _, ok := c.Plans[PlanFree]; !ok {
+		return errors.New("entitlements: no free plan")
+	}
+	for name, e := range c.Plans {
+		if e.MaxChirpLength <= 0 {
+			return fmt.Errorf("entitlements: plan %q: max_chirp_length must be positive", name)
+		}
+		if e.MaxScheduledChirps < 0 || e.ChirpsPerHour < 0 {
+			return fmt.Errorf("entitlements: plan %q: limits can't be negative", name)
+		}
+	}
+	for alias, plan := range c.Aliases {
+		if _, ok := c.Plans[alias]; ok {
+			return fmt.Errorf("entitlements: alias %q is also a plan", alias)
+		}
+		if _, ok := c.Plans[plan]; !ok {
+			return fmt.Errorf("entitlements: alias %q: no plan %q", alias, plan)
+		}
+	}
+	if _, ok := c.Plans[c.PaidFallback]; c.PaidFallback != "" && !ok {
+		return fmt.Errorf("entitlements: paid_fallback: no plan %q", c.PaidFallback)
+	}
+	return nil
+}
+
+// Parse reads a JSON configuration such as
+//
+//	{"plans": {"free": {"max_chirp_length": 140}, "red": {...}},
+//	 "aliases": {"red_yearly": "red"}, "paid_fallback": "red"}
+//
+// Fields left out of a plan are zero: no editing, no scheduling and no
+// rate limit.
+func Parse(r io.Reader) (*Config, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("entitlements: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// LoadFile is Parse for a file.
+func LoadFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
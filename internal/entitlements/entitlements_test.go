@@ -0,0 +1,114 @@
+package entitlements
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	c, err := LoadFile(filepath.Join("testdata", "plans.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	free := Entitlements{MaxChirpLength: 140, ChirpsPerHour: 10}
+	red := Entitlements{MaxChirpLength: 500, EditChirps: true, MaxScheduledChirps: 20, ChirpsPerHour: 100}
+	tests := []struct {
+		plan   string
+		want   Entitlements
+		wantOK bool
+	}{
+		{"free", free, true},
+		{"red", red, true},
+		{"red_yearly", red, true},
+		{"platinum", free, false},
+		{"", free, false},
+	}
+
+	for _, tt := range tests {
+		if got := c.For(tt.plan); got != tt.want {
+			t.Errorf("For(%q) = %+v, want %+v", tt.plan, got, tt.want)
+		}
+		if _, ok := c.Resolve(tt.plan); ok != tt.wantOK {
+			t.Errorf("Resolve(%q) ok = %v, want %v", tt.plan, ok, tt.wantOK)
+		}
+	}
+}
+
+func TestResolvePaidFallback(t *testing.T) {
+	c := Default()
+	tests := []struct {
+		plan string
+		want string
+	}{
+		{"red", "red"},
+		{"red_yearly", "red"},
+		{"free", "free"},
+		{"", "free"},
+	}
+
+	for _, tt := range tests {
+		if got, _ := c.Resolve(tt.plan); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.plan, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"No free plan", `{"plans": {"red": {"max_chirp_length": 500}}}`},
+		{"Zero chirp length", `{"plans": {"free": {}}}`},
+		{"Negative limit", `{"plans": {"free": {"max_chirp_length": 140, "chirps_per_hour": -1}}}`},
+		{"Unknown field", `{"plans": {"free": {"max_chirp_length": 140, "max_chirps": 3}}}`},
+		{"Not JSON", `plans: free`},
+		{"Alias to unknown plan", `{"plans": {"free": {"max_chirp_length": 140}}, "aliases": {"red_yearly": "red"}}`},
+		{"Alias shadows plan", `{"plans": {"free": {"max_chirp_length": 140}}, "aliases": {"free": "free"}}`},
+		{"Unknown paid fallback", `{"plans": {"free": {"max_chirp_length": 140}}, "paid_fallback": "red"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.input)); err == nil {
+				t.Error("Parse succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	c := Default()
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	free, red := c.For(PlanFree), c.For("red")
+	if free.MaxChirpLength != 140 {
+		t.Errorf("free MaxChirpLength = %d, want 140", free.MaxChirpLength)
+	}
+	if red.MaxChirpLength <= free.MaxChirpLength {
+		t.Errorf("red MaxChirpLength = %d, want more than free", red.MaxChirpLength)
+	}
+}
+
+func TestHas(t *testing.T) {
+	tests := []struct {
+		e    Entitlements
+		f    Feature
+		want bool
+	}{
+		{Entitlements{EditChirps: true}, FeatureEditChirps, true},
+		{Entitlements{}, FeatureEditChirps, false},
+		{Entitlements{MaxScheduledChirps: 1}, FeatureScheduledChirps, true},
+		{Entitlements{}, FeatureScheduledChirps, false},
+		{Entitlements{EditChirps: true}, Feature("teleport"), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.e.Has(tt.f); got != tt.want {
+			t.Errorf("%+v.Has(%q) = %v, want %v", tt.e, tt.f, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package entitlements
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter allows up to n events per period for each key, with the events
+// spread out rather than reset on the hour: after a burst of n, the next
+// one is allowed period/n later. Each key's n can differ per call, so a
+// user whose plan changes gets the new rate straight away.
+//
+// Allow checks and records an event in one step, so concurrent callers
+// can't all pass the check before any of them is counted. An event that
+// turns out not to happen is given back with Refund.
+//
+// State is kept in memory, so with several instances each applies the
+// limit on its own.
+type Limiter struct {
+	period time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+	// tat is each key's theoretical arrival time: when it would be back to
+	// a full allowance.
+	tat map[string]time.Time
+}
+
+func NewLimiter(period time.Duration) *Limiter {
+	return &Limiter{period: period, now: time.Now, tat: make(map[string]time.Time)}
+}
+
+// Allow records an event for key if it is within n per period. When it
+// isn't, Allow records nothing and returns false and how long until the
+// next event would be allowed. n <= 0 means no limit.
+func (l *Limiter) Allow(key string, n int) (bool, time.Duration) {
+	if n <= 0 {
+		return true, 0
+	}
+	interval := l.period / time.Duration(n)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	tat := l.tatLocked(key, now)
+	// Allowing this event moves tat on by one interval; it may be at most
+	// a full period ahead of now.
+	if wait := tat.Add(interval).Sub(now) - l.period; wait > 0 {
+		return false, wait
+	}
+	l.tat[key] = tat.Add(interval)
+	return true, 0
+}
+
+// Refund gives back an event Allow recorded for key with the same n.
+func (l *Limiter) Refund(key string, n int) {
+	if n <= 0 {
+		return
+	}
+	interval := l.period / time.Duration(n)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	tat := l.tatLocked(key, now).Add(-interval)
+	if !tat.After(now) {
+		delete(l.tat, key)
+		return
+	}
+	l.tat[key] = tat
+}
+
+func (l *Limiter) tatLocked(key string, now time.Time) time.Time {
+	tat, ok := l.tat[key]
+	if !ok || tat.Before(now) {
+		return now
+	}
+	return tat
+}
+
+// Sweep forgets the keys that are back to a full allowance. They behave the
+// same as keys never seen, so this only frees memory.
+func (l *Limiter) Sweep() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for k, t := range l.tat {
+		if t.Before(now) {
+			delete(l.tat, k)
+		}
+	}
+}
+
+// SweepEvery calls Sweep at every interval until ctx is done, so the
+// limiter only holds the keys active within the last period.
+func (l *Limiter) SweepEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Sweep()
+		}
+	}
+}
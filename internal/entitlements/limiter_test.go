@@ -0,0 +1,94 @@
+package entitlements
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	start := time.Unix(1767225600, 0)
+	now := start
+	l := NewLimiter(time.Hour)
+	l.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		at       time.Duration
+		key      string
+		n        int
+		keep     bool
+		want     bool
+		wantWait time.Duration
+	}{
+		{"First of burst", 0, "a", 3, true, true, 0},
+		{"Refunded events don't count", 0, "a", 3, false, true, 0},
+		{"Second of burst", 0, "a", 3, true, true, 0},
+		{"Third of burst", 0, "a", 3, true, true, 0},
+		{"Burst used up", 0, "a", 3, false, false, 20 * time.Minute},
+		{"Other keys are separate", 0, "b", 3, true, true, 0},
+		{"Too early for the next", 10 * time.Minute, "a", 3, false, false, 10 * time.Minute},
+		{"One interval later", 20 * time.Minute, "a", 3, true, true, 0},
+		{"Only one came back", 20 * time.Minute, "a", 3, false, false, 20 * time.Minute},
+		{"A lower rate would still wait", 30 * time.Minute, "a", 3, false, false, 10 * time.Minute},
+		{"A higher plan allows more sooner", 30 * time.Minute, "a", 6, true, true, 0},
+		{"No limit", 30 * time.Minute, "a", 0, true, true, 0},
+		{"Fully recovered after an idle period", 3 * time.Hour, "a", 3, true, true, 0},
+	}
+
+	for _, tt := range tests {
+		now = start.Add(tt.at)
+		got, wait := l.Allow(tt.key, tt.n)
+		if got != tt.want || wait != tt.wantWait {
+			t.Errorf("%s: Allow(%q, %d) = %v, %v; want %v, %v", tt.name, tt.key, tt.n, got, wait, tt.want, tt.wantWait)
+		}
+		if got && !tt.keep {
+			l.Refund(tt.key, tt.n)
+		}
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	start := time.Unix(1767225600, 0)
+	now := start
+	l := NewLimiter(time.Hour)
+	l.now = func() time.Time { return now }
+
+	l.Allow("idle", 60)
+	l.Allow("busy", 1)
+
+	now = start.Add(30 * time.Minute)
+	l.Sweep()
+
+	if _, ok := l.tat["idle"]; ok {
+		t.Error("Sweep() kept a key back to its full allowance")
+	}
+	if _, ok := l.tat["busy"]; !ok {
+		t.Error("Sweep() dropped a key that is still limited")
+	}
+	if ok, _ := l.Allow("busy", 1); ok {
+		t.Error("Allow() allowed a limited key after Sweep()")
+	}
+}
+
+func TestLimiterConcurrentBurst(t *testing.T) {
+	l := NewLimiter(time.Hour)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.Allow("a", 3); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 3 {
+		t.Errorf("%d of 20 concurrent events allowed, want 3", got)
+	}
+}
@@ -0,0 +1,148 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var errHeaderInjection = errors.New("header value contains a line break")
+
+// render formats msg as an RFC 5322 message with CRLF line endings.
+func render(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes(), nil
+}
+
+// SMTPMailer sends through an SMTP relay with PLAIN auth when credentials
+// are set.
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := render(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := strings.Cut(m.Addr, ":")
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	// net/smtp has no context support; run the send in the background so
+	// a cancelled context at least stops the caller from waiting on it.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FileMailer writes each message to its own .eml file in Dir, which is
+// handy for inspecting mail in development and tests.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := render(m.From, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(m.Dir, now.UTC().Format("20060102T150405.000000000")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LogMailer prints messages instead of sending them.
+type LogMailer struct {
+	Logger *log.Logger
+	From   string
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	data, err := render(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	logger := m.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("mail to %s:\n%s", msg.To, data)
+	return nil
+}
+
+// ValidAddress reports whether s is a bare email address such as
+// "alice@example.com", without a display name or angle brackets.
+func ValidAddress(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && addr.Name == ""
+}
+
+// Ensure the implementations satisfy Mailer.
+var (
+	_ Mailer = (*SMTPMailer)(nil)
+	_ Mailer = (*FileMailer)(nil)
+	_ Mailer = (*LogMailer)(nil)
+)
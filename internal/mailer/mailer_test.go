@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	got, err := render("Chirpy <no-reply@chirpy.test>", Message{
+		To:      "alice@example.com",
+		Subject: "Verify your email",
+		Body:    "line one\nline two",
+	}, now)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	want := "From: Chirpy <no-reply@chirpy.test>\r\n" +
+		"To: alice@example.com\r\n" +
+		"Subject: Verify your email\r\n" +
+		"Date: Sun, 01 Mar 2026 12:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		"line one\r\nline two"
+	if string(got) != want {
+		t.Errorf("render() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRenderRejectsBadHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{name: "Injected header in subject", msg: Message{To: "a@example.com", Subject: "hi\r\nBcc: victim@example.com"}},
+		{name: "Injected header in recipient", msg: Message{To: "a@example.com\nBcc: victim@example.com"}},
+		{name: "Invalid recipient", msg: Message{To: "not an address"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := render("no-reply@chirpy.test", tt.msg, time.Now()); err == nil {
+				t.Error("render() accepted a bad message")
+			}
+		})
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "no-reply@chirpy.test"}
+
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to, Subject: "hello", Body: "hi"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "To: alice@example.com\r\n") {
+		t.Errorf("first file does not contain the first message:\n%s", data)
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := &LogMailer{Logger: log.New(&buf, "", 0), From: "no-reply@chirpy.test"}
+	if err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "hello", Body: "token: abc"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "token: abc") {
+		t.Errorf("log output missing body: %q", buf.String())
+	}
+}
+
+func TestValidAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "alice@example.com", want: true},
+		{in: "alice+chirpy@mail.example.com", want: true},
+		{in: "Alice <alice@example.com>", want: false},
+		{in: "<alice@example.com>", want: false},
+		{in: "alice", want: false},
+		{in: "alice@", want: false},
+		{in: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ValidAddress(tt.in); got != tt.want {
+				t.Errorf("ValidAddress(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package moderation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// WordListFilter matches whole words after normalization, so leetspeak and
+// lookalike spellings hit the same entry as the plain word.
+type WordListFilter struct {
+	words map[string]Action
+}
+
+// NewWordListFilter builds a filter from words and the action each one
+// triggers. Words are normalized the same way chirp text is.
+func NewWordListFilter(words map[string]Action) *WordListFilter {
+	f := &WordListFilter{words: make(map[string]Action, len(words))}
+	for w, a := range words {
+		f.words[Normalize(w)] = a
+	}
+	return f
+}
+
+func (f *WordListFilter) Check(text string) []Match {
+	var matches []Match
+	for _, t := range tokenize(text) {
+		for _, c := range candidates(text, t) {
+			word := Normalize(text[c.start:c.end])
+			if a, ok := f.words[word]; ok {
+				matches = append(matches, Match{Start: c.start, End: c.end, Action: a, Rule: "word:" + word})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// ParseWordList reads one word per line, optionally followed by the action
+// to take ("mask" when omitted). Blank lines and lines starting with # are
+// ignored.
+func ParseWordList(r io.Reader) (map[string]Action, error) {
+	words := make(map[string]Action)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		action := ActionMask
+		switch len(fields) {
+		case 1:
+		case 2:
+			a, err := ParseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			action = a
+		default:
+			return nil, fmt.Errorf("line %d: expected \"word [action]\"", n)
+		}
+		words[fields[0]] = action
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// LoadWordListFile reads a word list in the ParseWordList format.
+func LoadWordListFile(path string) (map[string]Action, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseWordList(f)
+}
+
+// RegexFilter matches a regular expression against the original text.
+type RegexFilter struct {
+	name    string
+	pattern *regexp.Regexp
+	action  Action
+}
+
+func NewRegexFilter(name string, pattern *regexp.Regexp, action Action) *RegexFilter {
+	return &RegexFilter{name: name, pattern: pattern, action: action}
+}
+
+func (f *RegexFilter) Check(text string) []Match {
+	var matches []Match
+	for _, loc := range f.pattern.FindAllStringIndex(text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		matches = append(matches, Match{Start: loc[0], End: loc[1], Action: f.action, Rule: f.name})
+	}
+	return matches
+}
+
+// LoadRegexRules reads a JSON array of {"name", "pattern", "action"}
+// objects and compiles each into a RegexFilter.
+func LoadRegexRules(r io.Reader) ([]Filter, error) {
+	var rules []struct {
+		Name    string `json:"name"`
+		Pattern string `json:"pattern"`
+		Action  string `json:"action"`
+	}
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("decode regex rules: %w", err)
+	}
+
+	filters := make([]Filter, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d: missing name", i)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		action, err := ParseAction(rule.Action)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		filters = append(filters, NewRegexFilter(rule.Name, re, action))
+	}
+	return filters, nil
+}
+
+// LoadRegexRulesFile reads rules in the LoadRegexRules format.
+func LoadRegexRulesFile(path string) ([]Filter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadRegexRules(f)
+}
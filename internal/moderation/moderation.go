@@ -0,0 +1,140 @@
+package moderation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Action is what a filter wants done with the text it matched.
+type Action int
+
+const (
+	// ActionMask replaces the matched text with asterisks.
+	ActionMask Action = iota + 1
+	// ActionFlag lets the chirp through but queues it for human review.
+	ActionFlag
+	// ActionReject refuses the chirp outright.
+	ActionReject
+)
+
+// Mask is the replacement written over masked spans.
+const Mask = "****"
+
+func (a Action) String() string {
+	switch a {
+	case ActionMask:
+		return "mask"
+	case ActionFlag:
+		return "flag"
+	case ActionReject:
+		return "reject"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// ParseAction parses the names used in word lists, rule files and the
+// moderation_words table.
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "mask":
+		return ActionMask, nil
+	case "flag":
+		return ActionFlag, nil
+	case "reject":
+		return ActionReject, nil
+	}
+	return 0, fmt.Errorf("unknown moderation action %q", s)
+}
+
+// Match is a span of the original text a filter objected to. Start and End
+// are byte offsets into the text passed to Check.
+type Match struct {
+	Start  int
+	End    int
+	Action Action
+	Rule   string
+}
+
+// Filter inspects a chirp body and reports the spans it objects to.
+type Filter interface {
+	Check(text string) []Match
+}
+
+// Result is the outcome of running a Chain over a body.
+type Result struct {
+	// Text is the body with masked spans replaced by Mask. Everything else,
+	// including whitespace and punctuation, is left exactly as written.
+	Text     string
+	Rejected bool
+	Flagged  bool
+	Matches  []Match
+}
+
+// Reasons lists the distinct rules that matched with the given action.
+func (r Result) Reasons(action Action) []string {
+	var reasons []string
+	seen := map[string]bool{}
+	for _, m := range r.Matches {
+		if m.Action == action && !seen[m.Rule] {
+			seen[m.Rule] = true
+			reasons = append(reasons, m.Rule)
+		}
+	}
+	return reasons
+}
+
+// Chain runs filters in order and combines their matches.
+type Chain struct {
+	filters []Filter
+}
+
+func NewChain(filters ...Filter) *Chain {
+	return &Chain{filters: filters}
+}
+
+// Moderate runs every filter over text. All filters see the original text,
+// so masking by one filter never hides a match from another.
+func (c *Chain) Moderate(text string) Result {
+	res := Result{Text: text}
+	for _, f := range c.filters {
+		res.Matches = append(res.Matches, f.Check(text)...)
+	}
+
+	var masks []Match
+	for _, m := range res.Matches {
+		switch m.Action {
+		case ActionReject:
+			res.Rejected = true
+		case ActionFlag:
+			res.Flagged = true
+		case ActionMask:
+			masks = append(masks, m)
+		}
+	}
+	res.Text = applyMasks(text, masks)
+	return res
+}
+
+// applyMasks replaces each span with Mask, merging overlapping spans first
+// so a word hit by two filters is masked once.
+func applyMasks(text string, masks []Match) string {
+	if len(masks) == 0 {
+		return text
+	}
+	sort.Slice(masks, func(i, j int) bool { return masks[i].Start < masks[j].Start })
+
+	var b strings.Builder
+	pos := 0
+	for i := 0; i < len(masks); {
+		start, end := masks[i].Start, masks[i].End
+		for i++; i < len(masks) && masks[i].Start < end; i++ {
+			end = max(end, masks[i].End)
+		}
+		b.WriteString(text[pos:start])
+		b.WriteString(Mask)
+		pos = end
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
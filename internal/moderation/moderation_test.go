@@ -0,0 +1,188 @@
+package moderation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "Plain", in: "Kerfuffle", want: "kerfuffle"},
+		{name: "Leetspeak", in: "k3rfuffl3", want: "kerfuffle"},
+		{name: "Symbols", in: "$h@rbert", want: "sharbert"},
+		{name: "Cyrillic lookalikes", in: "fоrnах", want: "fornax"},
+		{name: "Fullwidth", in: "ＦＯＲＮＡＸ", want: "fornax"},
+		{name: "Combining marks", in: "fornáx", want: "fornax"},
+		{name: "Accented", in: "fòrnäx", want: "fornax"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainModerate(t *testing.T) {
+	words := NewWordListFilter(map[string]Action{
+		"kerfuffle": ActionMask,
+		"sharbert":  ActionMask,
+		"fornax":    ActionMask,
+		"spam":      ActionFlag,
+		"forbidden": ActionReject,
+	})
+	links := NewRegexFilter("link", regexp.MustCompile(`https?://\S+`), ActionFlag)
+	chain := NewChain(words, links)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantText string
+		rejected bool
+		flagged  bool
+	}{
+		{
+			name:     "Clean body is untouched",
+			body:     "I had something  interesting\tfor breakfast",
+			wantText: "I had something  interesting\tfor breakfast",
+		},
+		{
+			name:     "Trailing punctuation is kept",
+			body:     "What a kerfuffle! Really.",
+			wantText: "What a ****! Really.",
+		},
+		{
+			name:     "Whitespace is preserved",
+			body:     "  Sharbert,\n\nfornax  ",
+			wantText: "  ****,\n\n****  ",
+		},
+		{
+			name:     "Leetspeak",
+			body:     "such a k3rfuffl3",
+			wantText: "such a ****",
+		},
+		{
+			name:     "Leading symbol is a letter",
+			body:     "$harbert!!",
+			wantText: "****!!",
+		},
+		{
+			name:     "Confusables",
+			body:     "fоrnах time",
+			wantText: "**** time",
+		},
+		{
+			name:     "Substring is not a word",
+			body:     "kerfuffles are fine",
+			wantText: "kerfuffles are fine",
+		},
+		{
+			name:     "Flag keeps text",
+			body:     "buy spam at https://example.com",
+			wantText: "buy spam at https://example.com",
+			flagged:  true,
+		},
+		{
+			name:     "Reject",
+			body:     "this is F0rbidden",
+			wantText: "this is F0rbidden",
+			rejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chain.Moderate(tt.body)
+			if got.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", got.Text, tt.wantText)
+			}
+			if got.Rejected != tt.rejected {
+				t.Errorf("Rejected = %v, want %v", got.Rejected, tt.rejected)
+			}
+			if got.Flagged != tt.flagged {
+				t.Errorf("Flagged = %v, want %v", got.Flagged, tt.flagged)
+			}
+		})
+	}
+}
+
+func TestResultReasons(t *testing.T) {
+	chain := NewChain(
+		NewWordListFilter(map[string]Action{"spam": ActionFlag}),
+		NewRegexFilter("link", regexp.MustCompile(`https?://\S+`), ActionFlag),
+	)
+	got := chain.Moderate("spam spam http://a http://b").Reasons(ActionFlag)
+	want := []string{"word:spam", "link"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Reasons() = %v, want %v", got, want)
+	}
+}
+
+func TestOverlappingMasks(t *testing.T) {
+	chain := NewChain(
+		NewWordListFilter(map[string]Action{"fornax": ActionMask}),
+		NewRegexFilter("fornax-prefix", regexp.MustCompile(`forn\w*`), ActionMask),
+	)
+	if got := chain.Moderate("a fornax b").Text; got != "a **** b" {
+		t.Errorf("Text = %q, want %q", got, "a **** b")
+	}
+}
+
+func TestParseWordList(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]Action
+		wantErr bool
+	}{
+		{
+			name: "Default and explicit actions",
+			in:   "# comment\nkerfuffle\n\nspam flag\nforbidden reject\n",
+			want: map[string]Action{"kerfuffle": ActionMask, "spam": ActionFlag, "forbidden": ActionReject},
+		},
+		{
+			name:    "Unknown action",
+			in:      "kerfuffle delete\n",
+			wantErr: true,
+		},
+		{
+			name:    "Too many fields",
+			in:      "two words mask\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWordList(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWordList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseWordList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRegexRules(t *testing.T) {
+	filters, err := LoadRegexRules(strings.NewReader(`[{"name": "phone", "pattern": "\\d{3}-\\d{4}", "action": "mask"}]`))
+	if err != nil {
+		t.Fatalf("LoadRegexRules() error = %v", err)
+	}
+	if got := NewChain(filters...).Moderate("call 555-1234!").Text; got != "call ****!" {
+		t.Errorf("Text = %q, want %q", got, "call ****!")
+	}
+
+	if _, err := LoadRegexRules(strings.NewReader(`[{"name": "bad", "pattern": "(", "action": "mask"}]`)); err == nil {
+		t.Error("LoadRegexRules() accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,157 @@
+package moderation
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// leetspeak maps digits and symbols commonly substituted for letters.
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+// confusables maps non-Latin letters that render like Latin ones, plus
+// accented Latin letters, to their plain ASCII lookalike.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i',
+	'ї': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// Accented Latin
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a',
+	'ç': 'c', 'č': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ī': 'i', 'ı': 'i',
+	'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o',
+	'š': 's', 'ß': 's',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u',
+	'ý': 'y', 'ÿ': 'y',
+	'ž': 'z',
+}
+
+// foldLetter lower-cases r and maps lookalikes to ASCII. Fullwidth forms
+// are folded to their ASCII counterparts first.
+func foldLetter(r rune) rune {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	}
+	r = unicode.ToLower(r)
+	if c, ok := confusables[r]; ok {
+		return c
+	}
+	return r
+}
+
+// isTokenRune reports whether r can be part of a word, either as a letter
+// or digit or as a leetspeak stand-in for one.
+func isTokenRune(r rune) bool {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	}
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+		return true
+	}
+	_, ok := leetspeak[r]
+	return ok
+}
+
+// Normalize folds s into the form word lists are matched against: lower
+// case ASCII lookalikes with leetspeak substitutions undone and combining
+// marks dropped. "K3rfuffl3" and "ＫＥＲＦＵＦＦＬＥ" both
+// become "kerfuffle".
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = foldLetter(r)
+		if l, ok := leetspeak[r]; ok {
+			r = l
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// token is a candidate word in the original text.
+type token struct {
+	start, end int
+}
+
+// tokenize splits text into runs of token runes, reporting byte offsets into
+// the original string so matches can be masked in place.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if isTokenRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{start, len(text)})
+	}
+	return tokens
+}
+
+// candidates returns t and the variants of it with leading and/or trailing
+// symbols dropped, so "kerfuffle!" matches as "kerfuffle" followed by
+// punctuation while "$harbert!" still reads the "$" as an "s".
+func candidates(text string, t token) []token {
+	isSymbol := func(r rune) bool {
+		r = foldLetter(r)
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	}
+
+	lead := t
+	for lead.start < lead.end {
+		r, size := utf8.DecodeRuneInString(text[lead.start:lead.end])
+		if !isSymbol(r) {
+			break
+		}
+		lead.start += size
+	}
+	trail := t
+	for trail.end > trail.start {
+		r, size := utf8.DecodeLastRuneInString(text[trail.start:trail.end])
+		if !isSymbol(r) {
+			break
+		}
+		trail.end -= size
+	}
+	both := token{lead.start, trail.end}
+
+	out := []token{t}
+	for _, c := range []token{trail, lead, both} {
+		if c.start < c.end && !slices.Contains(out, c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+// Package subscription models a user's Chirpy Red subscription and how
+// Polka billing events change it.
+package subscription
+
+import (
+	"errors"
+	"time"
+)
+
+// Status is where a subscription is in its lifecycle.
+type Status string
+
+const (
+	// StatusActive renews at the end of the period.
+	StatusActive Status = "active"
+	// StatusPastDue had a renewal payment fail. Benefits last until the
+	// period ends, giving Polka time to retry.
+	StatusPastDue Status = "past_due"
+	// StatusCanceled won't renew but keeps its benefits until the period
+	// ends.
+	StatusCanceled Status = "canceled"
+	// StatusEnded was downgraded or refunded and has no benefits.
+	StatusEnded Status = "ended"
+)
+
+const (
+	PlanFree = "free"
+	PlanRed  = "red"
+)
+
+// Subscription is one user's subscription. A zero PeriodEnd means the
+// period is open-ended; subscriptions carried over from the old
+// is_chirpy_red flag have no end until Polka renews them.
+type Subscription struct {
+	Plan        string
+	Status      Status
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// LastEventAt is when the most recent applied event happened, so an
+	// older event delivered late doesn't undo a newer one.
+	LastEventAt time.Time
+}
+
+// Active reports whether the subscription grants its plan at now. Periods
+// lapse on their own; no event is needed for a subscription to expire.
+func (s Subscription) Active(now time.Time) bool {
+	switch s.Status {
+	case StatusActive, StatusPastDue, StatusCanceled:
+		return s.PeriodEnd.IsZero() || now.Before(s.PeriodEnd)
+	}
+	return false
+}
+
+// EventType is the Polka event name.
+type EventType string
+
+const (
+	EventUpgraded      EventType = "user.upgraded"
+	EventRenewed       EventType = "user.subscription_renewed"
+	EventCanceled      EventType = "user.subscription_canceled"
+	EventDowngraded    EventType = "user.downgraded"
+	EventPaymentFailed EventType = "user.payment_failed"
+	EventRefunded      EventType = "user.payment_refunded"
+)
+
+// Known reports whether t is an event Apply understands.
+func (t EventType) Known() bool {
+	switch t {
+	case EventUpgraded, EventRenewed, EventCanceled, EventDowngraded, EventPaymentFailed, EventRefunded:
+		return true
+	}
+	return false
+}
+
+// Event is a billing event. Plan and the period are optional; when Polka
+// leaves the period out it runs for a month from the event.
+type Event struct {
+	Type        EventType
+	Plan        string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	At          time.Time
+}
+
+var (
+	ErrUnknownEvent   = errors.New("subscription: unknown event")
+	ErrNoSubscription = errors.New("subscription: event for a user without a subscription")
+	ErrStaleEvent     = errors.New("subscription: event is older than the last one applied")
+)
+
+// Apply returns the subscription after ev. exists is false when the user
+// has never subscribed. Applying the same event twice gives the same
+// result, except for a renewal without an explicit period, which extends
+// the period again; callers dedupe events by ID for that case.
+func Apply(sub Subscription, exists bool, ev Event) (Subscription, error) {
+	if !ev.Type.Known() {
+		return sub, ErrUnknownEvent
+	}
+	if exists && ev.At.Before(sub.LastEventAt) {
+		return sub, ErrStaleEvent
+	}
+	if !exists && ev.Type != EventUpgraded && ev.Type != EventRenewed {
+		return sub, ErrNoSubscription
+	}
+
+	next := sub
+	next.LastEventAt = ev.At
+
+	switch ev.Type {
+	case EventUpgraded:
+		next.Plan = planOr(ev.Plan, PlanRed)
+		// Upgrading an already running subscription keeps its period
+		// unless Polka says otherwise, so a repeated upgrade is harmless.
+		if !exists || !sub.Active(ev.At) || !ev.PeriodEnd.IsZero() {
+			next.PeriodStart, next.PeriodEnd = period(ev, ev.At)
+		}
+		next.Status = StatusActive
+
+	case EventRenewed:
+		if !exists {
+			next.Plan = planOr(ev.Plan, PlanRed)
+		} else if ev.Plan != "" {
+			next.Plan = ev.Plan
+		}
+		next.Status = StatusActive
+		if exists && !ev.PeriodEnd.IsZero() && sub.PeriodEnd.Equal(ev.PeriodEnd) {
+			break // this renewal was already applied
+		}
+		// A renewal continues from the end of the current period, or
+		// starts afresh if that already lapsed.
+		from := ev.At
+		if exists && sub.PeriodEnd.After(ev.At) {
+			from = sub.PeriodEnd
+		}
+		next.PeriodStart, next.PeriodEnd = period(ev, from)
+
+	case EventCanceled:
+		if sub.Status != StatusEnded {
+			next.Status = StatusCanceled
+		}
+
+	case EventPaymentFailed:
+		if sub.Status == StatusActive || sub.Status == StatusCanceled {
+			next.Status = StatusPastDue
+		}
+
+	case EventDowngraded:
+		// Moving to another paid plan keeps the period; dropping to free
+		// ends the subscription now.
+		if ev.Plan != "" && ev.Plan != PlanFree {
+			next.Plan = ev.Plan
+			break
+		}
+		next.Status = StatusEnded
+		next.PeriodEnd = ev.At
+
+	case EventRefunded:
+		next.Status = StatusEnded
+		next.PeriodEnd = ev.At
+	}
+	return next, nil
+}
+
+func planOr(plan, def string) string {
+	if plan == "" {
+		return def
+	}
+	return plan
+}
+
+// period returns the event's period, defaulting to a month starting at from.
+func period(ev Event, from time.Time) (time.Time, time.Time) {
+	start := ev.PeriodStart
+	if start.IsZero() {
+		start = from
+	}
+	end := ev.PeriodEnd
+	if end.IsZero() {
+		end = start.AddDate(0, 1, 0)
+	}
+	return start, end
+}
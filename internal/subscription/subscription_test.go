@@ -0,0 +1,183 @@
+package subscription
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	t0       = time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	monthEnd = t0.AddDate(0, 1, 0)
+)
+
+func active() Subscription {
+	return Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: t0, PeriodEnd: monthEnd, LastEventAt: t0}
+}
+
+func TestApply(t *testing.T) {
+	later := t0.Add(24 * time.Hour)
+	explicitEnd := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		sub     Subscription
+		exists  bool
+		ev      Event
+		want    Subscription
+		wantErr error
+	}{
+		{
+			name:   "First upgrade starts a month",
+			ev:     Event{Type: EventUpgraded, At: t0},
+			want:   active(),
+			exists: false,
+		},
+		{
+			name:   "Upgrade with explicit period and plan",
+			ev:     Event{Type: EventUpgraded, Plan: "red_yearly", PeriodStart: t0, PeriodEnd: explicitEnd, At: t0},
+			want:   Subscription{Plan: "red_yearly", Status: StatusActive, PeriodStart: t0, PeriodEnd: explicitEnd, LastEventAt: t0},
+			exists: false,
+		},
+		{
+			name:   "Repeated upgrade keeps the running period",
+			sub:    active(),
+			exists: true,
+			ev:     Event{Type: EventUpgraded, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: t0, PeriodEnd: monthEnd, LastEventAt: later},
+		},
+		{
+			name:   "Upgrade after expiry starts a new period",
+			sub:    active(),
+			exists: true,
+			ev:     Event{Type: EventUpgraded, At: monthEnd.Add(time.Hour)},
+			want: Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: monthEnd.Add(time.Hour),
+				PeriodEnd: monthEnd.Add(time.Hour).AddDate(0, 1, 0), LastEventAt: monthEnd.Add(time.Hour)},
+		},
+		{
+			name:   "Renewal continues from the period end",
+			sub:    active(),
+			exists: true,
+			ev:     Event{Type: EventRenewed, At: monthEnd.Add(-time.Hour)},
+			want: Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: monthEnd,
+				PeriodEnd: monthEnd.AddDate(0, 1, 0), LastEventAt: monthEnd.Add(-time.Hour)},
+		},
+		{
+			name:   "Renewal clears past due",
+			sub:    Subscription{Plan: PlanRed, Status: StatusPastDue, PeriodStart: t0, PeriodEnd: monthEnd, LastEventAt: t0},
+			exists: true,
+			ev:     Event{Type: EventRenewed, PeriodEnd: explicitEnd, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: monthEnd, PeriodEnd: explicitEnd, LastEventAt: later},
+		},
+		{
+			name:   "Repeated renewal with explicit period is a no-op",
+			sub:    Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: monthEnd, PeriodEnd: explicitEnd, LastEventAt: later},
+			exists: true,
+			ev:     Event{Type: EventRenewed, PeriodEnd: explicitEnd, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: monthEnd, PeriodEnd: explicitEnd, LastEventAt: later},
+		},
+		{
+			name:   "Cancellation keeps benefits until period end",
+			sub:    active(),
+			exists: true,
+			ev:     Event{Type: EventCanceled, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusCanceled, PeriodStart: t0, PeriodEnd: monthEnd, LastEventAt: later},
+		},
+		{
+			name:   "Payment failure marks past due",
+			sub:    active(),
+			exists: true,
+			ev:     Event{Type: EventPaymentFailed, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusPastDue, PeriodStart: t0, PeriodEnd: monthEnd, LastEventAt: later},
+		},
+		{
+			name:   "Downgrade to free ends now",
+			sub:    active(),
+			exists: true,
+			ev:     Event{Type: EventDowngraded, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusEnded, PeriodStart: t0, PeriodEnd: later, LastEventAt: later},
+		},
+		{
+			name:   "Downgrade to another paid plan keeps the period",
+			sub:    Subscription{Plan: "red_plus", Status: StatusActive, PeriodStart: t0, PeriodEnd: monthEnd, LastEventAt: t0},
+			exists: true,
+			ev:     Event{Type: EventDowngraded, Plan: PlanRed, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusActive, PeriodStart: t0, PeriodEnd: monthEnd, LastEventAt: later},
+		},
+		{
+			name:   "Refund ends now",
+			sub:    active(),
+			exists: true,
+			ev:     Event{Type: EventRefunded, At: later},
+			want:   Subscription{Plan: PlanRed, Status: StatusEnded, PeriodStart: t0, PeriodEnd: later, LastEventAt: later},
+		},
+		{
+			name:    "Stale event is ignored",
+			sub:     Subscription{Plan: PlanRed, Status: StatusEnded, PeriodStart: t0, PeriodEnd: later, LastEventAt: later},
+			exists:  true,
+			ev:      Event{Type: EventRenewed, At: t0},
+			want:    Subscription{Plan: PlanRed, Status: StatusEnded, PeriodStart: t0, PeriodEnd: later, LastEventAt: later},
+			wantErr: ErrStaleEvent,
+		},
+		{
+			name:    "Cancellation without a subscription",
+			ev:      Event{Type: EventCanceled, At: t0},
+			wantErr: ErrNoSubscription,
+		},
+		{
+			name:    "Unknown event",
+			sub:     active(),
+			exists:  true,
+			ev:      Event{Type: "user.deleted", At: later},
+			want:    active(),
+			wantErr: ErrUnknownEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.sub, tt.exists, tt.ev)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+
+			// Applying the same event again changes nothing, apart from
+			// renewals without an explicit period.
+			if err != nil || (tt.ev.Type == EventRenewed && tt.ev.PeriodEnd.IsZero()) {
+				return
+			}
+			again, err := Apply(got, true, tt.ev)
+			if err != nil || again != got {
+				t.Errorf("second Apply() = %+v, %v; want %+v", again, err, got)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	tests := []struct {
+		name string
+		sub  Subscription
+		at   time.Time
+		want bool
+	}{
+		{name: "Active within period", sub: active(), at: t0.Add(time.Hour), want: true},
+		{name: "Active after period end", sub: active(), at: monthEnd, want: false},
+		{name: "Past due within period", sub: Subscription{Status: StatusPastDue, PeriodEnd: monthEnd}, at: t0, want: true},
+		{name: "Canceled within period", sub: Subscription{Status: StatusCanceled, PeriodEnd: monthEnd}, at: t0, want: true},
+		{name: "Canceled after period end", sub: Subscription{Status: StatusCanceled, PeriodEnd: monthEnd}, at: monthEnd.Add(time.Second), want: false},
+		{name: "Ended", sub: Subscription{Status: StatusEnded, PeriodEnd: monthEnd}, at: t0, want: false},
+		{name: "Open-ended legacy subscription", sub: Subscription{Status: StatusActive}, at: t0, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sub.Active(tt.at); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for endpoints on loopback, private,
+// link-local or other non-public addresses, which would let whoever
+// registers an endpoint reach internal services through Chirpy.
+var ErrPrivateAddress = errors.New("webhook: endpoint address is not public")
+
+// sharedAddressSpace is carrier-grade NAT (RFC 6598), which netip doesn't
+// count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicAddr reports whether a is a public unicast address.
+func PublicAddr(a netip.Addr) bool {
+	a = a.Unmap()
+	return a.IsValid() &&
+		a.IsGlobalUnicast() &&
+		!a.IsPrivate() &&
+		!a.IsLoopback() &&
+		!a.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(a)
+}
+
+// CheckEndpointHost resolves the host of raw and fails with
+// ErrPrivateAddress if any of its addresses isn't public.
+func CheckEndpointHost(ctx context.Context, resolver *net.Resolver, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEndpoint, err)
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook: resolving %s: %w", u.Hostname(), err)
+	}
+	for _, a := range addrs {
+		if !PublicAddr(a) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, a)
+		}
+	}
+	return nil
+}
+
+// NewPublicClient returns an HTTP client that only connects to public
+// addresses. The check happens when connecting, after DNS resolution, so a
+// hostname that changes to point inside the network after registration is
+// still refused. It ignores proxy settings, since the proxy's address
+// would be checked instead of the endpoint's.
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !PublicAddr(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// A redirect could point anywhere; deliveries don't follow them.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
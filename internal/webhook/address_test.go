@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := PublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEndpointHost(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{"https://93.184.216.34/hook", nil},
+		{"https://127.0.0.1/hook", ErrPrivateAddress},
+		{"https://[::1]:8443/hook", ErrPrivateAddress},
+		{"http://169.254.169.254/latest/meta-data", ErrPrivateAddress},
+		{"https://10.0.0.5/hook", ErrPrivateAddress},
+		{"https://localhost/hook", ErrPrivateAddress},
+	}
+
+	for _, tt := range tests {
+		err := CheckEndpointHost(context.Background(), nil, tt.url)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckEndpointHost(%q) = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPublicClientRefusesLoopback(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	t.Cleanup(srv.Close)
+
+	s := &Sender{Client: NewPublicClient(time.Second)}
+	a := s.Send(context.Background(), srv.URL, "evt_1", fixedNow, []byte(`{}`), endpointSecret)
+	if !errors.Is(a.Err, ErrPrivateAddress) {
+		t.Errorf("Send to loopback: err = %v, want %v", a.Err, ErrPrivateAddress)
+	}
+	if hit {
+		t.Error("the loopback server received the delivery")
+	}
+}
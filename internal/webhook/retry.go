@@ -0,0 +1,31 @@
+package webhook
+
+import "time"
+
+// RetryPolicy spaces out attempts at a delivery or at processing one:
+// each retry waits twice as long as the one before, from BaseDelay up to
+// MaxDelay, and after MaxAttempts the work is dead-lettered.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy makes eight attempts over roughly two hours.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 8, BaseDelay: 30 * time.Second, MaxDelay: time.Hour}
+
+// Next returns how long to wait after the given number of failed attempts,
+// or false once no attempts are left.
+func (p RetryPolicy) Next(attempts int) (time.Duration, bool) {
+	if attempts >= p.MaxAttempts {
+		return 0, false
+	}
+	d := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay, true
+		}
+	}
+	return min(d, p.MaxDelay), true
+}
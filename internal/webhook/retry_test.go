@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyNext(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 6, BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+		wantOK   bool
+	}{
+		{1, 10 * time.Second, true},
+		{2, 20 * time.Second, true},
+		{3, 40 * time.Second, true},
+		{4, time.Minute, true},
+		{5, time.Minute, true},
+		{6, 0, false},
+		{7, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := p.Next(tt.attempts)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Next(%d) = %v, %v; want %v, %v", tt.attempts, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultMaxResponseBytes is how much of an endpoint's response a Sender
+// keeps by default.
+const DefaultMaxResponseBytes = 4 << 10
+
+var (
+	ErrInvalidEndpoint  = errors.New("webhook: endpoint must be an absolute https URL")
+	ErrUnexpectedStatus = errors.New("webhook: endpoint did not respond with 2xx")
+)
+
+// ValidateEndpointURL checks that raw is somewhere deliveries can be sent:
+// an absolute https URL without credentials. allowHTTP also accepts plain
+// http, for local development.
+func ValidateEndpointURL(raw string, allowHTTP bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEndpoint, err)
+	}
+	if u.Host == "" || u.User != nil || u.Fragment != "" {
+		return ErrInvalidEndpoint
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if allowHTTP {
+			return nil
+		}
+	}
+	return ErrInvalidEndpoint
+}
+
+// GenerateSecret returns a random signing secret for a new endpoint.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Attempt is the outcome of sending one delivery.
+type Attempt struct {
+	// StatusCode is zero when no response arrived.
+	StatusCode int
+	// Response is the start of the response body, for the attempt log.
+	Response string
+	Duration time.Duration
+	// Err is nil only for a 2xx response.
+	Err error
+}
+
+// OK reports whether the endpoint accepted the delivery.
+func (a Attempt) OK() bool {
+	return a.Err == nil
+}
+
+// Sender posts signed deliveries to endpoints.
+type Sender struct {
+	// Client defaults to http.DefaultClient; set one with a timeout.
+	Client *http.Client
+	// MaxResponseBytes caps how much of a response body is read, and
+	// defaults to DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+}
+
+// Send posts body to url, signed with secrets as in NewSignedRequest. Any
+// 2xx response counts as delivered; everything else, including a network
+// error, is reported in the Attempt rather than as a separate error so it
+// can be logged and retried the same way.
+func (s *Sender) Send(ctx context.Context, url, id string, at time.Time, body []byte, secrets ...[]byte) Attempt {
+	req, err := NewSignedRequest(url, id, at, body, secrets...)
+	if err != nil {
+		return Attempt{Err: err}
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	limit := s.MaxResponseBytes
+	if limit <= 0 {
+		limit = DefaultMaxResponseBytes
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return Attempt{Duration: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	// Drain a little more so the connection can be reused.
+	io.CopyN(io.Discard, resp.Body, limit)
+	a := Attempt{
+		StatusCode: resp.StatusCode,
+		Response:   string(respBody),
+		Duration:   time.Since(start),
+	}
+	switch {
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		a.Err = fmt.Errorf("%w: got %d", ErrUnexpectedStatus, resp.StatusCode)
+	case err != nil:
+		a.Err = err
+	}
+	return a
+}
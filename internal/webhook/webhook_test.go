@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var (
+	oldSecret = []byte("polka-old-secret")
+	newSecret = []byte("polka-new-secret")
+	// fixedNow is the clock every test delivery is checked against.
+	fixedNow = time.Unix(1767225600, 0)
+)
+
+func loadFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// signedHeaders signs a fixture the way Polka would.
+func signedHeaders(id string, at time.Time, body []byte, secrets ...[]byte) http.Header {
+	h := http.Header{}
+	SetHeaders(h, id, at, body, secrets...)
+	return h
+}
+
+func TestVerifyFixtures(t *testing.T) {
+	v := &Verifier{Secrets: [][]byte{newSecret, oldSecret}, Now: func() time.Time { return fixedNow }}
+	upgraded := loadFixture(t, "user_upgraded.json")
+	failed := loadFixture(t, "user_payment_failed.json")
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		wantErr error
+	}{
+		{
+			name:   "Signed with the new secret",
+			header: signedHeaders("msg_1", fixedNow, upgraded, newSecret),
+			body:   upgraded,
+		},
+		{
+			name:   "Signed with the old secret during rotation",
+			header: signedHeaders("msg_2", fixedNow, failed, oldSecret),
+			body:   failed,
+		},
+		{
+			name:   "Signed with both secrets",
+			header: signedHeaders("msg_3", fixedNow, upgraded, []byte("retired"), newSecret),
+			body:   upgraded,
+		},
+		{
+			name:   "Clock skew within tolerance",
+			header: signedHeaders("msg_4", fixedNow.Add(-4*time.Minute), upgraded, newSecret),
+			body:   upgraded,
+		},
+		{
+			name:    "Unknown secret",
+			header:  signedHeaders("msg_5", fixedNow, upgraded, []byte("attacker")),
+			body:    upgraded,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Body swapped for another fixture",
+			header:  signedHeaders("msg_6", fixedNow, upgraded, newSecret),
+			body:    failed,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Too old",
+			header:  signedHeaders("msg_7", fixedNow.Add(-6*time.Minute), upgraded, newSecret),
+			body:    upgraded,
+			wantErr: ErrTimestampExpired,
+		},
+		{
+			name:    "Too far in the future",
+			header:  signedHeaders("msg_8", fixedNow.Add(6*time.Minute), upgraded, newSecret),
+			body:    upgraded,
+			wantErr: ErrTimestampExpired,
+		},
+		{
+			name:    "No headers",
+			header:  http.Header{},
+			body:    upgraded,
+			wantErr: ErrMissingHeaders,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := v.Verify(tt.header, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && id != tt.header.Get(HeaderID) {
+				t.Errorf("Verify() id = %q, want %q", id, tt.header.Get(HeaderID))
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	v := &Verifier{Secrets: [][]byte{newSecret}, Now: func() time.Time { return fixedNow }}
+	body := loadFixture(t, "user_upgraded.json")
+
+	tests := []struct {
+		name   string
+		tamper func(h http.Header)
+		want   error
+	}{
+		{name: "Changed delivery ID", tamper: func(h http.Header) { h.Set(HeaderID, "msg_other") }, want: ErrInvalidSignature},
+		{name: "Changed timestamp", tamper: func(h http.Header) { h.Set(HeaderTimestamp, strconv.FormatInt(fixedNow.Unix()+1, 10)) }, want: ErrInvalidSignature},
+		{name: "Malformed timestamp", tamper: func(h http.Header) { h.Set(HeaderTimestamp, "yesterday") }, want: ErrInvalidTimestamp},
+		{name: "Unknown version", tamper: func(h http.Header) { h.Set(HeaderSignature, "v2"+h.Get(HeaderSignature)[2:]) }, want: ErrInvalidSignature},
+		{name: "Not base64", tamper: func(h http.Header) { h.Set(HeaderSignature, "v1,!!!") }, want: ErrInvalidSignature},
+		{name: "Bare API key", tamper: func(h http.Header) { h.Set(HeaderSignature, "polka-new-secret") }, want: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := signedHeaders("msg_1", fixedNow, body, newSecret)
+			tt.tamper(h)
+			if _, err := v.Verify(h, body); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignGolden(t *testing.T) {
+	var golden struct {
+		Fixture   string `json:"fixture"`
+		Secret    string `json:"secret"`
+		ID        string `json:"id"`
+		Timestamp int64  `json:"timestamp"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(loadFixture(t, "golden.json"), &golden); err != nil {
+		t.Fatal(err)
+	}
+	body := loadFixture(t, golden.Fixture)
+	at := time.Unix(golden.Timestamp, 0)
+
+	if got := Sign(golden.ID, at, body, []byte(golden.Secret)); got != golden.Signature {
+		t.Errorf("Sign() = %s, want %s", got, golden.Signature)
+	}
+
+	v := &Verifier{Secrets: [][]byte{[]byte(golden.Secret)}, Now: func() time.Time { return at }}
+	h := http.Header{}
+	h.Set(HeaderID, golden.ID)
+	h.Set(HeaderTimestamp, strconv.FormatInt(golden.Timestamp, 10))
+	h.Set(HeaderSignature, golden.Signature)
+	if _, err := v.Verify(h, body); err != nil {
+		t.Errorf("Verify() of golden delivery error = %v", err)
+	}
+}
+
+func TestNewSignedRequest(t *testing.T) {
+	body := loadFixture(t, "user_upgraded.json")
+	req, err := NewSignedRequest("http://localhost:8080/api/polka/webhooks", "msg_1", fixedNow, body, newSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("NewSignedRequest() = %s with Content-Type %q", req.Method, req.Header.Get("Content-Type"))
+	}
+	v := &Verifier{Secrets: [][]byte{newSecret}, Now: func() time.Time { return fixedNow }}
+	if _, err := v.Verify(req.Header, body); err != nil {
+		t.Errorf("Verify() of signed request error = %v", err)
+	}
+}
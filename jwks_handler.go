@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+)
+
+// loadJWTKeys builds the access-token key set. With JWT_SIGNING_KEY_FILE
+// unset tokens are HS256-signed with SECRETKEY as before. Otherwise the PEM
+// key in that file signs (kid JWT_SIGNING_KEY_ID), and JWT_VERIFY_KEYS lists
+// retired keys, as comma-separated kid=path pairs, that are still accepted
+// until their tokens expire. SECRETKEY keeps verifying HS256 tokens until
+// SECRETKEY_RETIRE_AT, an RFC 3339 time, so switching from it doesn't log
+// everyone out; without that time HS256 tokens are no longer accepted.
+func loadJWTKeys(secret string) (*auth.KeySet, error) {
+	signingFile := os.Getenv("JWT_SIGNING_KEY_FILE")
+	if signingFile == "" {
+		return auth.NewHMACKeySet(secret), nil
+	}
+
+	data, err := os.ReadFile(signingFile)
+	if err != nil {
+		return nil, err
+	}
+	signing, err := auth.ParsePrivateKeyPEM(os.Getenv("JWT_SIGNING_KEY_ID"), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", signingFile, err)
+	}
+
+	var verify []*auth.Key
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("JWT_VERIFY_KEYS entry %q is not kid=path", entry)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		k, err := auth.ParseKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		verify = append(verify, k)
+	}
+
+	if retire := os.Getenv("SECRETKEY_RETIRE_AT"); secret != "" && retire != "" {
+		retireAt, err := time.Parse(time.RFC3339, retire)
+		if err != nil {
+			return nil, fmt.Errorf("SECRETKEY_RETIRE_AT: %w", err)
+		}
+		if retireAt.After(time.Now()) {
+			verify = append(verify, auth.NewHMACKey(secret).RetireAt(retireAt))
+		}
+	}
+
+	return auth.NewKeySet(signing, verify...)
+}
+
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.jwtKeys.JWKS())
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+)
+
+func TestLoadJWTKeysRetiresSecretKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := auth.NewHMACKeySet("secret").MakeAccessToken(uuid.New(), 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		retireAt   string
+		wantErr    bool
+		wantLegacy bool
+	}{
+		{name: "Unset", wantLegacy: false},
+		{name: "Future", retireAt: time.Now().Add(time.Hour).Format(time.RFC3339), wantLegacy: true},
+		{name: "Past", retireAt: time.Now().Add(-time.Minute).Format(time.RFC3339), wantLegacy: false},
+		{name: "Malformed", retireAt: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SIGNING_KEY_FILE", keyFile)
+			t.Setenv("JWT_SIGNING_KEY_ID", "k1")
+			t.Setenv("JWT_VERIFY_KEYS", "")
+			t.Setenv("SECRETKEY_RETIRE_AT", tt.retireAt)
+
+			ks, err := loadJWTKeys("secret")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadJWTKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := ks.ParseAccessToken(legacy); (err == nil) != tt.wantLegacy {
+				t.Errorf("HS256 token accepted = %v, want %v", err == nil, tt.wantLegacy)
+			}
+		})
+	}
+}
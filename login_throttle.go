@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// Failed logins are counted per account (by the email typed, whether or
+// not it exists) and per client IP. The IP allowance is larger because
+// many users can share an address.
+//
+// Password reset requests go through the same table under their own
+// scopes: every request counts, since each one can send an email.
+const (
+	loginScopeAccount = "account"
+	loginScopeIP      = "ip"
+	resetScopeAccount = "reset_account"
+	resetScopeIP      = "reset_ip"
+)
+
+var loginLockoutPolicies = map[string]auth.LockoutPolicy{
+	loginScopeAccount: {FreeAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 15 * time.Minute, ResetAfter: 24 * time.Hour},
+	loginScopeIP:      {FreeAttempts: 50, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, ResetAfter: 24 * time.Hour},
+	resetScopeAccount: {FreeAttempts: 3, BaseDelay: 5 * time.Minute, MaxDelay: time.Hour, ResetAfter: time.Hour},
+	resetScopeIP:      {FreeAttempts: 20, BaseDelay: time.Minute, MaxDelay: time.Hour, ResetAfter: 24 * time.Hour},
+}
+
+type loginKey struct {
+	scope string
+	key   string
+}
+
+func accountLoginKey(email string) loginKey {
+	return loginKey{scope: loginScopeAccount, key: strings.ToLower(strings.TrimSpace(email))}
+}
+
+func loginKeys(r *http.Request, email string) []loginKey {
+	return []loginKey{accountLoginKey(email), {scope: loginScopeIP, key: clientIP(r)}}
+}
+
+func passwordResetKeys(r *http.Request, email string) []loginKey {
+	return []loginKey{
+		{scope: resetScopeAccount, key: accountLoginKey(email).key},
+		{scope: resetScopeIP, key: clientIP(r)},
+	}
+}
+
+// LockoutEvent is one lockout as shown to admins.
+type LockoutEvent struct {
+	ID          uuid.UUID `json:"id"`
+	Scope       string    `json:"scope"`
+	Key         string    `json:"key"`
+	Failures    int32     `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// loginLockedUntil returns the end of the longest lockout among keys, or
+// the zero time when none is in force.
+func (cfg *apiConfig) loginLockedUntil(ctx context.Context, keys []loginKey) (time.Time, error) {
+	var until time.Time
+	now := time.Now()
+	for _, k := range keys {
+		f, err := cfg.db.GetLoginFailure(ctx, database.GetLoginFailureParams{Scope: k.scope, Key: k.key})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return time.Time{}, err
+		}
+		if f.LockedUntil.Valid && f.LockedUntil.Time.After(now) && f.LockedUntil.Time.After(until) {
+			until = f.LockedUntil.Time
+		}
+	}
+	return until, nil
+}
+
+// recordLoginFailure counts a failed attempt against every key and locks
+// out the ones that have run past their free attempts. Each lockout is
+// recorded for GET /admin/lockouts.
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, r *http.Request, keys []loginKey) {
+	now := time.Now()
+	for _, k := range keys {
+		policy := loginLockoutPolicies[k.scope]
+		f, err := cfg.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Scope:        k.scope,
+			Key:          k.key,
+			LastFailedAt: now,
+			ResetBefore:  now.Add(-policy.ResetAfter),
+		})
+		if err != nil {
+			log.Printf("Couldn't record login failure for %s %q: %v", k.scope, k.key, err)
+			continue
+		}
+
+		delay := policy.Delay(int(f.Failures))
+		if delay == 0 {
+			continue
+		}
+		until := now.Add(delay)
+		err = cfg.withTx(ctx, func(q *database.Queries) error {
+			if err := q.LockLogin(ctx, database.LockLoginParams{
+				Scope:       k.scope,
+				Key:         k.key,
+				LockedUntil: sql.NullTime{Time: until, Valid: true},
+			}); err != nil {
+				return err
+			}
+			return q.CreateLockoutEvent(ctx, database.CreateLockoutEventParams{
+				ID:          uuid.New(),
+				Scope:       k.scope,
+				Key:         k.key,
+				Failures:    f.Failures,
+				LockedUntil: until,
+				IpAddress:   clientIP(r),
+				UserAgent:   r.UserAgent(),
+				CreatedAt:   now,
+			})
+		})
+		if err != nil {
+			log.Printf("Couldn't lock out %s %q: %v", k.scope, k.key, err)
+			continue
+		}
+		log.Printf("Locked out %s %q for %s after %d attempts", k.scope, k.key, delay, f.Failures)
+	}
+}
+
+// clearLoginFailures forgets an account's failures after a complete
+// login. The IP count is left to expire so one valid account can't be
+// used to reset it.
+func (cfg *apiConfig) clearLoginFailures(ctx context.Context, email string) {
+	key := accountLoginKey(email)
+	if err := cfg.db.ClearLoginFailures(ctx, database.ClearLoginFailuresParams{Scope: key.scope, Key: key.key}); err != nil {
+		log.Printf("Couldn't clear login failures: %v", err)
+	}
+}
+
+// checkLoginLockout writes a 429 with Retry-After and reports false when
+// any of keys is locked out.
+func (cfg *apiConfig) checkLoginLockout(w http.ResponseWriter, r *http.Request, keys []loginKey) bool {
+	return cfg.checkLockout(w, r, keys, "Too many failed login attempts, try again later")
+}
+
+func (cfg *apiConfig) checkLockout(w http.ResponseWriter, r *http.Request, keys []loginKey, msg string) bool {
+	until, err := cfg.loginLockedUntil(r.Context(), keys)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+		return false
+	}
+	if until.IsZero() {
+		return true
+	}
+	wait := math.Ceil(time.Until(until).Seconds())
+	w.Header().Set("Retry-After", fmt.Sprint(int(max(wait, 1))))
+	respondWithError(w, http.StatusTooManyRequests, msg, nil)
+	return false
+}
+
+func (cfg *apiConfig) handlerListLockoutEvents(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePageParams(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	events, err := cfg.db.ListLockoutEvents(r.Context(), database.ListLockoutEventsParams{
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageLimit:       limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list lockout events", err)
+		return
+	}
+
+	if len(events) > int(limit) {
+		events = events[:limit]
+		last := events[len(events)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	resp := make([]LockoutEvent, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, LockoutEvent{
+			ID:          e.ID,
+			Scope:       e.Scope,
+			Key:         e.Key,
+			Failures:    e.Failures,
+			LockedUntil: e.LockedUntil,
+			IPAddress:   e.IpAddress,
+			UserAgent:   e.UserAgent,
+			CreatedAt:   e.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
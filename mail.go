@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/natnael-alemayehu/chirpy/internal/mailer"
+)
+
+const mailSendTimeout = 30 * time.Second
+
+// newMailerFromEnv picks the mail transport from MAILER: "smtp" relays
+// through SMTP_ADDR, "file" writes .eml files to MAIL_DIR, and "log" (the
+// default) prints messages to the server log.
+func newMailerFromEnv() (mailer.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@localhost>"
+	}
+
+	switch kind := os.Getenv("MAILER"); kind {
+	case "", "log":
+		return &mailer.LogMailer{From: from}, nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		return &mailer.FileMailer{Dir: dir, From: from}, nil
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("SMTP_ADDR must be set when MAILER=smtp")
+		}
+		return &mailer.SMTPMailer{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", kind)
+	}
+}
+
+// sendMailAsync delivers msg in the background so a slow relay never holds
+// up a request. Failures are only logged.
+func (cfg *apiConfig) sendMailAsync(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := cfg.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Couldn't send %q mail to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
@@ -1,25 +1,64 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"sync/atomic"
 	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
 	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/entitlements"
+	"github.com/natnael-alemayehu/chirpy/internal/mailer"
+	"github.com/natnael-alemayehu/chirpy/internal/moderation"
+	"github.com/natnael-alemayehu/chirpy/internal/webhook"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
-	secret         string
+	jwtKeys        *auth.KeySet
 	polkaKey       string
+	adminKey       string
+	polkaVerifier  *webhook.Verifier
+	webhookWorker  *backgroundWorker
+	// webhookDispatcher sends outbox events to registered endpoints.
+	webhookDispatcher *backgroundWorker
+	webhookSender     *webhook.Sender
+	// webhookAllowPrivate lets endpoints point at loopback and private
+	// addresses, for local development only.
+	webhookAllowPrivate bool
+
+	trendingWindow   time.Duration
+	trendingHalfLife time.Duration
+
+	tokenVersions  *tokenVersionCache
+	passwordParams auth.PasswordParams
+
+	mailer               mailer.Mailer
+	requireVerifiedEmail bool
+
+	entitlements *entitlements.Config
+	chirpLimiter *entitlements.Limiter
+	// chirpScheduler publishes scheduled chirps when they are due.
+	chirpScheduler *backgroundWorker
+
+	// passwordResets queues the emails of password reset requests for
+	// runPasswordResets.
+	passwordResets chan string
+
+	moderator           atomic.Pointer[moderation.Chain]
+	moderationWordsFile string
+	moderationRulesFile string
 }
 
 func main() {
@@ -41,15 +80,47 @@ func main() {
 	}
 
 	secret := os.Getenv("SECRETKEY")
-	if platform == "" {
+	if secret == "" {
 		log.Fatal("SECRETKEY must be set")
 	}
 
 	polkaKey := os.Getenv("POLKAKEY")
-	if platform == "" {
-		log.Fatal("POLKAKEY must be set")
+
+	polkaVerifier, err := newPolkaVerifier(polkaKey)
+	if err != nil {
+		log.Fatalf("Polka webhook setup err: %v", err)
+	}
+
+	jwtKeys, err := loadJWTKeys(secret)
+	if err != nil {
+		log.Fatalf("JWT key setup err: %v", err)
 	}
 
+	mail, err := newMailerFromEnv()
+	if err != nil {
+		log.Fatalf("mailer setup err: %v", err)
+	}
+
+	defaults := auth.DefaultPasswordParams()
+	passwordParams := auth.PasswordParams{
+		Memory:      uint32(uintEnv("ARGON2_MEMORY_KIB", uint64(defaults.Memory), 32)),
+		Iterations:  uint32(uintEnv("ARGON2_ITERATIONS", uint64(defaults.Iterations), 32)),
+		Parallelism: uint8(uintEnv("ARGON2_PARALLELISM", uint64(defaults.Parallelism), 8)),
+	}
+	if err := passwordParams.Validate(); err != nil {
+		log.Fatalf("password hashing setup err: %v", err)
+	}
+
+	plans, err := loadEntitlements()
+	if err != nil {
+		log.Fatalf("entitlements setup err: %v", err)
+	}
+
+	allowPrivateEndpoints := boolEnv("WEBHOOK_ALLOW_PRIVATE_ENDPOINTS", false)
+
+	trendingWindow := durationEnv("TRENDING_WINDOW", 24*time.Hour)
+	trendingHalfLife := durationEnv("TRENDING_HALF_LIFE", 6*time.Hour)
+
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatalf("SQL open err: %v", err)
@@ -65,36 +136,123 @@ func main() {
 	}
 
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             dbQueries,
-		platform:       platform,
-		secret:         secret,
-		polkaKey:       polkaKey,
+		fileserverHits:      atomic.Int32{},
+		db:                  dbQueries,
+		dbConn:              db,
+		platform:            platform,
+		jwtKeys:             jwtKeys,
+		polkaKey:            polkaKey,
+		adminKey:            os.Getenv("ADMIN_API_KEY"),
+		polkaVerifier:       polkaVerifier,
+		webhookWorker:       newBackgroundWorker(durationEnv("WEBHOOK_WORKER_INTERVAL", 5*time.Second)),
+		webhookDispatcher:   newBackgroundWorker(durationEnv("WEBHOOK_WORKER_INTERVAL", 5*time.Second)),
+		webhookSender:       newWebhookSender(allowPrivateEndpoints),
+		webhookAllowPrivate: allowPrivateEndpoints,
+
+		trendingWindow:   trendingWindow,
+		trendingHalfLife: trendingHalfLife,
+
+		tokenVersions:  newTokenVersionCache(durationEnv("TOKEN_VERSION_CACHE_TTL", 30*time.Second)),
+		passwordParams: passwordParams,
+
+		mailer:               mail,
+		requireVerifiedEmail: boolEnv("REQUIRE_VERIFIED_EMAIL", false),
+
+		entitlements:   plans,
+		chirpLimiter:   entitlements.NewLimiter(time.Hour),
+		chirpScheduler: newBackgroundWorker(durationEnv("SCHEDULED_CHIRPS_INTERVAL", 30*time.Second)),
+		passwordResets: make(chan string, passwordResetQueueSize),
+
+		moderationWordsFile: os.Getenv("MODERATION_WORDS_FILE"),
+		moderationRulesFile: os.Getenv("MODERATION_RULES_FILE"),
+	}
+
+	moderator, err := apiCfg.loadModerator(context.Background())
+	if err != nil {
+		log.Fatalf("moderation setup err: %v", err)
 	}
+	apiCfg.moderator.Store(moderator)
 
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir("./")))))
 
 	mux.HandleFunc("GET /api/healthz", handlerReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handlerJWKS)
 
 	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
 
 	mux.Handle("POST /admin/reset", apiCfg.middlewareCheckPlatform(http.HandlerFunc(apiCfg.handlerReset)))
+	mux.Handle("POST /admin/moderation/reload", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerReloadModeration)))
+	mux.Handle("GET /admin/moderation/flags", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerListChirpFlags)))
+	mux.Handle("POST /admin/moderation/flags/{flagID}/resolve", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerResolveChirpFlag)))
+	mux.Handle("GET /admin/lockouts", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerListLockoutEvents)))
+	mux.Handle("GET /admin/webhooks/events", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerListWebhookEvents)))
+	mux.Handle("POST /admin/webhooks/events/replay", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerReplayWebhookEvents)))
+	mux.Handle("POST /admin/webhooks/events/{eventID}/replay", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerReplayWebhookEvent)))
+	mux.Handle("POST /admin/webhooks/endpoints", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerCreateWebhookEndpoint)))
+	mux.Handle("GET /admin/webhooks/endpoints", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerListWebhookEndpoints)))
+	mux.Handle("DELETE /admin/webhooks/endpoints/{endpointID}", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerDeleteWebhookEndpoint)))
+	mux.Handle("GET /admin/webhooks/endpoints/{endpointID}/deliveries", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerListWebhookDeliveries)))
+	mux.Handle("GET /admin/webhooks/deliveries/{deliveryID}/attempts", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerListWebhookDeliveryAttempts)))
+	mux.Handle("POST /admin/webhooks/deliveries/{deliveryID}/retry", apiCfg.middlewareAdmin(http.HandlerFunc(apiCfg.handlerRetryWebhookDelivery)))
 
 	// chirp related endpoints
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirps)
+	mux.Handle("POST /api/chirps", apiCfg.middlewareChirpRateLimit(http.HandlerFunc(apiCfg.handlerCreateChirps)))
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerListChirps)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.handlerSearchChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirpsByID)
+	mux.Handle("PUT /api/chirps/{chirpID}", apiCfg.middlewareRequireEntitlement(entitlements.FeatureEditChirps, http.HandlerFunc(apiCfg.handlerUpdateChirp)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.handlerListChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.handlerGetChirpThread)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", apiCfg.handlerLikeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", apiCfg.handlerUnlikeChirp)
+	mux.Handle("POST /api/chirps/{chirpID}/rechirp", apiCfg.middlewareChirpRateLimit(http.HandlerFunc(apiCfg.handlerRechirp)))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.handlerUndoRechirp)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
+	mux.Handle("POST /api/scheduled-chirps", apiCfg.middlewareRequireEntitlement(entitlements.FeatureScheduledChirps,
+		apiCfg.middlewareChirpRateLimit(http.HandlerFunc(apiCfg.handlerCreateScheduledChirp))))
+	mux.HandleFunc("GET /api/scheduled-chirps", apiCfg.handlerListScheduledChirps)
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{scheduledID}", apiCfg.handlerDeleteScheduledChirp)
+
+	// Hashtag endpoints
+	mux.HandleFunc("GET /api/hashtags/trending", apiCfg.handlerTrendingHashtags)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.handlerListHashtagChirps)
 
 	// User related end point
 	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
 	mux.HandleFunc("PUT /api/users", apiCfg.hanlderUpdateUser)
+	mux.HandleFunc("POST /api/users/verify", apiCfg.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", apiCfg.handlerResendEmailVerification)
+	mux.HandleFunc("GET /api/users/me/mentions", apiCfg.handlerListMyMentions)
+	mux.HandleFunc("GET /api/users/me/subscription", apiCfg.handlerGetMySubscription)
+	mux.HandleFunc("GET /api/users/me/entitlements", apiCfg.handlerGetMyEntitlements)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerUpdateSubscription)
 
+	// Social graph endpoints
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handlerFollowUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handlerUnfollowUser)
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.handlerListFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.handlerListFollowing)
+	mux.HandleFunc("GET /api/timeline", apiCfg.handlerTimeline)
+
 	// Auth related endpoints
 	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.handlerLoginMFA)
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefreshToken)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevokeRefreshToken)
+	mux.HandleFunc("GET /api/sessions", apiCfg.handlerListSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.handlerRevokeSession)
+	mux.HandleFunc("POST /api/logout-all", apiCfg.handlerLogoutAll)
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.handlerForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handlerResetPassword)
+	mux.HandleFunc("POST /api/mfa/totp/enroll", apiCfg.handlerEnrollTOTP)
+	mux.HandleFunc("POST /api/mfa/totp/confirm", apiCfg.handlerConfirmTOTP)
+	mux.HandleFunc("DELETE /api/mfa/totp", apiCfg.handlerDisableTOTP)
+
+	go apiCfg.webhookWorker.run(context.Background(), "Webhook worker", apiCfg.processNextWebhookEvent)
+	go apiCfg.webhookDispatcher.run(context.Background(), "Webhook dispatcher", apiCfg.dispatchNextWebhook)
+	go apiCfg.chirpScheduler.run(context.Background(), "Chirp scheduler", apiCfg.publishNextScheduledChirp)
+	go apiCfg.runPasswordResets(context.Background())
+	go apiCfg.chirpLimiter.SweepEvery(context.Background(), 10*time.Minute)
 
 	fmt.Println("Serving on port: " + port)
 	err = srv.ListenAndServe()
@@ -103,6 +261,48 @@ func main() {
 	}
 }
 
+// durationEnv reads an optional time.ParseDuration value from the
+// environment, falling back to def when it is unset.
+func durationEnv(name string, def time.Duration) time.Duration {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration: %q", name, s)
+	}
+	return d
+}
+
+// uintEnv reads an optional unsigned integer of at most bits bits from the
+// environment, falling back to def when it is unset.
+func uintEnv(name string, def uint64, bits int) uint64 {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	n, err := strconv.ParseUint(s, 10, bits)
+	if err != nil {
+		log.Fatalf("%s must be an unsigned %d-bit integer: %q", name, bits, s)
+	}
+	return n
+}
+
+// boolEnv reads an optional strconv.ParseBool value from the environment,
+// falling back to def when it is unset.
+func boolEnv(name string, def bool) bool {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		log.Fatalf("%s must be a boolean: %q", name, s)
+	}
+	return b
+}
+
 func (a *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "text/html")
 	w.WriteHeader(http.StatusOK)
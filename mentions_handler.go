@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// handlerListMyMentions is the caller's mention feed: chirps that @mention
+// them, newest first.
+func (cfg *apiConfig) handlerListMyMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	chrps, err := cfg.db.ListChirpsMentioningUser(r.Context(), database.ListChirpsMentioningUserParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageLimit:       limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list mentions error", err)
+		return
+	}
+
+	if len(chrps) > int(limit) {
+		chrps = chrps[:limit]
+		last := chrps[len(chrps)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	chirpApps, err := cfg.chirpApps(r.Context(), chrps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "list mentions error", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpApps)
+}
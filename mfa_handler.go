@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+const (
+	totpIssuer        = "Chirpy"
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var errInvalidMFACode = errors.New("invalid or already used code")
+
+// userTOTP accepts codes one period either side of now.
+func userTOTP(secret []byte) auth.TOTP {
+	return auth.TOTP{Secret: secret, Skew: 1}
+}
+
+// respondWithMFAChallenge answers a correct password for an account with
+// two-factor authentication. The challenge is exchanged at /api/login/mfa.
+func (cfg *apiConfig) respondWithMFAChallenge(w http.ResponseWriter, usr database.User) {
+	type response struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}
+
+	challenge, err := cfg.jwtKeys.MakeMFAChallenge(usr.ID, usr.TokenVersion, mfaChallengeTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "JWT creation error", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response{
+		MFARequired: true,
+		MFAToken:    challenge,
+	})
+}
+
+// verifySecondFactor checks a TOTP code or, failing that, a recovery code.
+// Each TOTP period and each recovery code is accepted once.
+func verifySecondFactor(ctx context.Context, q *database.Queries, usr database.User, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		n, err := q.ConsumeRecoveryCode(ctx, database.ConsumeRecoveryCodeParams{
+			UserID:   usr.ID,
+			CodeHash: auth.HashOneTimeToken(auth.NormalizeRecoveryCode(recoveryCode)),
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	secret, err := auth.DecodeTOTPSecret(usr.TotpSecret.String)
+	if err != nil {
+		return err
+	}
+	step, ok := userTOTP(secret).Verify(code, time.Now())
+	if !ok {
+		return errInvalidMFACode
+	}
+	n, err := q.AdvanceUserTOTPStep(ctx, database.AdvanceUserTOTPStepParams{
+		ID:           usr.ID,
+		TotpLastStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+	if param.Code == "" && param.RecoveryCode == "" {
+		respondWithError(w, http.StatusBadRequest, "code or recovery_code is required", nil)
+		return
+	}
+
+	challenge, err := cfg.jwtKeys.ParseMFAChallenge(param.MFAToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "MFA token is invalid or expired", err)
+		return
+	}
+
+	usr, err := cfg.db.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "MFA token is invalid or expired", err)
+		return
+	}
+	// A password reset or logout-all since the challenge was issued ends it.
+	if usr.TokenVersion != challenge.TokenVersion || !usr.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusUnauthorized, "MFA token is invalid or expired", nil)
+		return
+	}
+
+	// Wrong codes count against the same allowance as wrong passwords.
+	keys := loginKeys(r, usr.Email)
+	if !cfg.checkLoginLockout(w, r, keys) {
+		return
+	}
+
+	if err := verifySecondFactor(r.Context(), cfg.db, usr, param.Code, param.RecoveryCode); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			cfg.recordLoginFailure(r.Context(), r, keys)
+			respondWithError(w, http.StatusUnauthorized, "Incorrect code", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check code", err)
+		return
+	}
+
+	cfg.clearLoginFailures(r.Context(), usr.Email)
+	cfg.respondWithLogin(w, r, usr)
+}
+
+func (cfg *apiConfig) handlerEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	usr, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate secret", err)
+		return
+	}
+	encoded := auth.EncodeTOTPSecret(secret)
+
+	// Enrolling again before confirming replaces the pending secret; once
+	// confirmed, two-factor has to be disabled first.
+	n, err := cfg.db.SetUserTOTPSecret(r.Context(), database.SetUserTOTPSecretParams{
+		ID:         userID,
+		TotpSecret: sql.NullString{String: encoded, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save secret", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Secret:     encoded,
+		OTPAuthURI: userTOTP(secret).URI(totpIssuer, usr.Email),
+	})
+}
+
+func (cfg *apiConfig) handlerConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+
+	usr, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+	if usr.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if !usr.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "Enroll before confirming", nil)
+		return
+	}
+
+	secret, err := auth.DecodeTOTPSecret(usr.TotpSecret.String)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Stored secret is invalid", err)
+		return
+	}
+	step, ok := userTOTP(secret).Verify(param.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Incorrect code", nil)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate recovery codes", err)
+		return
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		n, err := q.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+			ID:           userID,
+			TotpLastStep: step,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		if err := q.DeleteUserRecoveryCodes(r.Context(), userID); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			if err := q.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+				ID:        uuid.New(),
+				UserID:    userID,
+				CodeHash:  auth.HashOneTimeToken(auth.NormalizeRecoveryCode(code)),
+				CreatedAt: time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't enable two-factor authentication", err)
+		return
+	}
+
+	// The plain codes are only ever shown here.
+	respondWithJSON(w, http.StatusOK, response{RecoveryCodes: codes})
+}
+
+func (cfg *apiConfig) handlerDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+
+	usr, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+	if !usr.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is not enabled", nil)
+		return
+	}
+
+	// A stolen access token alone isn't enough to turn the second factor off.
+	// Wrong codes count against the account like wrong passwords, so the
+	// code can't be guessed by brute force either.
+	keys := []loginKey{accountLoginKey(usr.Email)}
+	if !cfg.checkLoginLockout(w, r, keys) {
+		return
+	}
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if err := verifySecondFactor(r.Context(), q, usr, param.Code, param.RecoveryCode); err != nil {
+			return err
+		}
+		if err := q.DisableUserTOTP(r.Context(), userID); err != nil {
+			return err
+		}
+		return q.DeleteUserRecoveryCodes(r.Context(), userID)
+	})
+	if err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			cfg.recordLoginFailure(r.Context(), r, keys)
+			respondWithError(w, http.StatusUnauthorized, "Incorrect code", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't disable two-factor authentication", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
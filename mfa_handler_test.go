@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// fakeLoginFailures keeps login_failures in memory for the fake database.
+func fakeLoginFailures(queries map[string]fakeQuery) {
+	failures := map[[2]string]database.LoginFailure{}
+	row := func(f database.LoginFailure) fakeResult {
+		return fakeRow(f.Scope, f.Key, int64(f.Failures), f.LastFailedAt, fakeNull(f.LockedUntil.Time, f.LockedUntil.Valid))
+	}
+	queries["GetLoginFailure"] = func(args []driver.Value) (fakeResult, error) {
+		f, ok := failures[[2]string{args[0].(string), args[1].(string)}]
+		if !ok {
+			return fakeNoRows(5), nil
+		}
+		return row(f), nil
+	}
+	queries["RecordLoginFailure"] = func(args []driver.Value) (fakeResult, error) {
+		k := [2]string{args[0].(string), args[1].(string)}
+		f := failures[k]
+		f.Scope, f.Key = k[0], k[1]
+		f.Failures++
+		f.LastFailedAt = args[2].(time.Time)
+		failures[k] = f
+		return row(f), nil
+	}
+	queries["LockLogin"] = func(args []driver.Value) (fakeResult, error) {
+		k := [2]string{args[0].(string), args[1].(string)}
+		f := failures[k]
+		f.LockedUntil = sql.NullTime{Time: args[2].(time.Time), Valid: true}
+		failures[k] = f
+		return fakeResult{affected: 1}, nil
+	}
+	queries["CreateLockoutEvent"] = func([]driver.Value) (fakeResult, error) {
+		return fakeResult{affected: 1}, nil
+	}
+}
+
+func TestDisableTOTPLocksOutWrongCodes(t *testing.T) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	usr := database.User{
+		ID:            uuid.New(),
+		Email:         "alice@example.com",
+		TotpSecret:    sql.NullString{String: auth.EncodeTOTPSecret(secret), Valid: true},
+		TotpEnabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	queries := map[string]fakeQuery{
+		"GetUserTokenVersion": func([]driver.Value) (fakeResult, error) { return fakeRow(int64(0)), nil },
+		"GetUserByID":         func([]driver.Value) (fakeResult, error) { return fakeUserRow(usr), nil },
+	}
+	fakeLoginFailures(queries)
+	cfg := newFakeDB(t, queries)
+	cfg.jwtKeys = auth.NewHMACKeySet("secret")
+
+	token, err := cfg.jwtKeys.MakeAccessToken(usr.ID, 0, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The account allows five wrong codes, and the sixth starts a lockout.
+	free := loginLockoutPolicies[loginScopeAccount].FreeAttempts
+	for i := 1; i <= free+2; i++ {
+		req := httptest.NewRequest(http.MethodDelete, "/api/mfa/totp", strings.NewReader(`{"code": "abcdef"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		cfg.handlerDisableTOTP(rec, req)
+
+		want := http.StatusUnauthorized
+		if i > free+1 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Fatalf("attempt %d: status = %d, want %d", i, rec.Code, want)
+		}
+		if want == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Error("429 without Retry-After")
+		}
+	}
+}
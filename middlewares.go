@@ -1,8 +1,11 @@
 package main
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
+
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
 )
 
 func (a *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -21,3 +24,25 @@ func (a *apiConfig) middlewareCheckPlatform(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// middlewareAdmin requires the ADMIN_API_KEY, sent as
+// "Authorization: ApiKey <key>". Without a configured key the admin API is
+// off.
+func (a *apiConfig) middlewareAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.adminKey == "" {
+			respondWithError(w, http.StatusForbidden, "Admin API is disabled", nil)
+			return
+		}
+		key, err := auth.GetAPIKey(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Admin API key required", err)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(key), []byte(a.adminKey)) != 1 {
+			respondWithError(w, http.StatusUnauthorized, "Invalid admin API key", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareAdmin(t *testing.T) {
+	tests := []struct {
+		name     string
+		adminKey string
+		header   string
+		want     int
+	}{
+		{name: "Valid key", adminKey: "s3cret", header: "ApiKey s3cret", want: http.StatusNoContent},
+		{name: "Wrong key", adminKey: "s3cret", header: "ApiKey nope", want: http.StatusUnauthorized},
+		{name: "No key sent", adminKey: "s3cret", header: "", want: http.StatusUnauthorized},
+		{name: "Admin API disabled", adminKey: "", header: "ApiKey s3cret", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{adminKey: tt.adminKey}
+			h := cfg.middlewareAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/lockouts", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/moderation"
+)
+
+type ChirpFlag struct {
+	ID        uuid.UUID `json:"id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Reasons   []string  `json:"reasons"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// loadModerator builds the moderation chain from the moderation_words table
+// plus the optional word list and regex rule files. File entries win over
+// table entries for the same word.
+func (cfg *apiConfig) loadModerator(ctx context.Context) (*moderation.Chain, error) {
+	rows, err := cfg.db.ListModerationWords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list moderation words: %w", err)
+	}
+	words := make(map[string]moderation.Action, len(rows))
+	for _, row := range rows {
+		action, err := moderation.ParseAction(row.Action)
+		if err != nil {
+			return nil, fmt.Errorf("moderation word %q: %w", row.Word, err)
+		}
+		words[row.Word] = action
+	}
+
+	if cfg.moderationWordsFile != "" {
+		fileWords, err := moderation.LoadWordListFile(cfg.moderationWordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", cfg.moderationWordsFile, err)
+		}
+		for w, a := range fileWords {
+			words[w] = a
+		}
+	}
+
+	filters := []moderation.Filter{moderation.NewWordListFilter(words)}
+	if cfg.moderationRulesFile != "" {
+		rules, err := moderation.LoadRegexRulesFile(cfg.moderationRulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", cfg.moderationRulesFile, err)
+		}
+		filters = append(filters, rules...)
+	}
+	return moderation.NewChain(filters...), nil
+}
+
+// flagChirp records a review flag when the moderation result asks for one.
+func flagChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, res moderation.Result) error {
+	if !res.Flagged {
+		return nil
+	}
+	return q.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+		ID:        uuid.New(),
+		ChirpID:   chirpID,
+		Reasons:   res.Reasons(moderation.ActionFlag),
+		CreatedAt: time.Now(),
+	})
+}
+
+func (cfg *apiConfig) handlerReloadModeration(w http.ResponseWriter, r *http.Request) {
+	chain, err := cfg.loadModerator(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation rules", err)
+		return
+	}
+	cfg.moderator.Store(chain)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListChirpFlags(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePageParams(r, false)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	flags, err := cfg.db.ListOpenChirpFlags(r.Context(), database.ListOpenChirpFlagsParams{
+		AfterCreatedAt: cursor.CreatedAt,
+		AfterID:        cursor.ID,
+		PageLimit:      limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list flags", err)
+		return
+	}
+
+	if len(flags) > int(limit) {
+		flags = flags[:limit]
+		last := flags[len(flags)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	resp := make([]ChirpFlag, 0, len(flags))
+	for _, f := range flags {
+		resp = append(resp, ChirpFlag{
+			ID:        f.ID,
+			ChirpID:   f.ChirpID,
+			Reasons:   f.Reasons,
+			CreatedAt: f.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerResolveChirpFlag(w http.ResponseWriter, r *http.Request) {
+	flagID, err := uuid.Parse(r.PathValue("flagID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid flag ID", err)
+		return
+	}
+
+	n, err := cfg.db.ResolveChirpFlag(r.Context(), flagID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve flag", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "open flag not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// Events sent to outbound webhook endpoints.
+const (
+	outboxChirpCreated = "chirp.created"
+	outboxChirpDeleted = "chirp.deleted"
+	outboxUserCreated  = "user.created"
+	outboxUserUpgraded = "user.upgraded"
+)
+
+var outboxEventTypes = []string{outboxChirpCreated, outboxChirpDeleted, outboxUserCreated, outboxUserUpgraded}
+
+// OutboxEnvelope is the body of every outbound webhook. ID is also sent as
+// the Webhook-Id header and stays the same across retries, so receivers can
+// drop duplicates.
+type OutboxEnvelope struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// UserCreatedEvent is the data of a user.created event. It leaves out the
+// email address, which receivers have no need for.
+type UserCreatedEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChirpDeletedEvent is the data of a chirp.deleted event.
+type ChirpDeletedEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// UserUpgradedEvent is the data of a user.upgraded event.
+type UserUpgradedEvent struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	Plan             string     `json:"plan"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end"`
+}
+
+// emitEvent writes an event to the outbox. q must be the transaction that
+// makes the change the event describes: the event is sent only if that
+// transaction commits, and is never lost once it has. Callers wake the
+// dispatcher after committing.
+func emitEvent(ctx context.Context, q *database.Queries, eventType string, data any) error {
+	env := OutboxEnvelope{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		ID:        env.ID,
+		EventType: eventType,
+		Payload:   payload,
+		CreatedAt: env.CreatedAt,
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// pageCursor is the (created_at, id) key of the last row on a page.
+// Clients only ever see it base64 encoded.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// firstPageCursor returns a key that sorts before (asc) or after (desc)
+// every row so the first page can use the same keyset query as the rest.
+func firstPageCursor(desc bool) pageCursor {
+	if desc {
+		return pageCursor{
+			CreatedAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+			ID:        uuid.Max,
+		}
+	}
+	return pageCursor{
+		CreatedAt: time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.Nil,
+	}
+}
+
+func (c pageCursor) encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePageCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor: %w", err)
+	}
+	micros, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor: %w", err)
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor: %w", err)
+	}
+	return pageCursor{CreatedAt: time.UnixMicro(usec).UTC(), ID: uid}, nil
+}
+
+// parsePageParams reads the `limit` and `cursor` query parameters.
+func parsePageParams(r *http.Request, desc bool) (int32, pageCursor, error) {
+	limit := int32(defaultPageLimit)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return 0, pageCursor{}, errors.New("limit must be a positive integer")
+		}
+		limit = int32(min(n, maxPageLimit))
+	}
+
+	cursor := firstPageCursor(desc)
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodePageCursor(s)
+		if err != nil {
+			return 0, pageCursor{}, err
+		}
+		cursor = c
+	}
+	return limit, cursor, nil
+}
+
+// setNextPageHeaders advertises the next page through `X-Next-Cursor` and a
+// RFC 8288 `Link` header that keeps the caller's other query parameters.
+func setNextPageHeaders(w http.ResponseWriter, r *http.Request, next pageCursor) {
+	cursor := next.encode()
+
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	u := *r.URL
+	u.RawQuery = q.Encode()
+
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
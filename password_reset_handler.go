@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/mailer"
+)
+
+const (
+	userTokenPasswordReset = "password_reset"
+	passwordResetTTL       = time.Hour
+	passwordResetTimeout   = 30 * time.Second
+	// passwordResetQueueSize bounds the requests waiting to be sent. Past
+	// it requests are dropped rather than piling up goroutines.
+	passwordResetQueueSize = 100
+)
+
+func (cfg *apiConfig) handlerForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+
+	// Requests are counted per email, whether or not it exists, and per IP,
+	// so the endpoint can't be used to flood an inbox.
+	keys := passwordResetKeys(r, param.Email)
+	if !cfg.checkLockout(w, r, keys, "Too many password reset requests, try again later") {
+		return
+	}
+	cfg.recordLoginFailure(r.Context(), r, keys)
+
+	// The lookup and token work happen after the response so neither the
+	// status nor the timing tells the caller whether the account exists.
+	select {
+	case cfg.passwordResets <- param.Email:
+	default:
+		log.Printf("Password reset queue full, dropping request")
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// runPasswordResets sends the queued password resets one at a time until
+// ctx is done.
+func (cfg *apiConfig) runPasswordResets(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case email := <-cfg.passwordResets:
+			sendCtx, cancel := context.WithTimeout(ctx, passwordResetTimeout)
+			if err := cfg.sendPasswordReset(sendCtx, email); err != nil {
+				log.Printf("Couldn't send password reset: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	usr, err := cfg.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	// A token that is still good was mailed already; sending another would
+	// only invalidate it.
+	active, err := cfg.db.HasActiveUserToken(ctx, database.HasActiveUserTokenParams{
+		UserID:  usr.ID,
+		Purpose: userTokenPasswordReset,
+	})
+	if err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+
+	token, err := cfg.issueUserToken(ctx, usr.ID, userTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      usr.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for this Chirpy account.\n\n"+
+			"To choose a new password, send the token below to POST /api/password/reset as {\"token\": \"...\", \"password\": \"...\"}:\n\n"+
+			"%s\n\nThe token expires in %s and works once. If you didn't ask for this, ignore this email; your password hasn't changed.\n",
+			token, passwordResetTTL),
+	})
+	if err != nil {
+		// A token that never arrived mustn't hold up the next request.
+		return errors.Join(err, cfg.db.InvalidateUserTokens(ctx, database.InvalidateUserTokensParams{
+			UserID:  usr.ID,
+			Purpose: userTokenPasswordReset,
+		}))
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+	if param.Token == "" || param.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "token and password are required", nil)
+		return
+	}
+
+	hash, err := auth.HashPasswordWithParams(param.Password, cfg.passwordParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Password hashing failed", err)
+		return
+	}
+
+	// Every existing session ends with the reset: refresh tokens are revoked
+	// and the token version bump rejects outstanding access tokens.
+	var tok database.UserToken
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		tok, err = q.ConsumeUserToken(r.Context(), database.ConsumeUserTokenParams{
+			TokenHash: auth.HashOneTimeToken(param.Token),
+			Purpose:   userTokenPasswordReset,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             tok.UserID,
+			HashedPassword: hash,
+		}); err != nil {
+			return err
+		}
+		if err := q.RevokeAllUserRefreshTokens(r.Context(), tok.UserID); err != nil {
+			return err
+		}
+		_, err = q.BumpUserTokenVersion(r.Context(), tok.UserID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, "Reset token is invalid or expired", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+	cfg.tokenVersions.forget(tok.UserID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
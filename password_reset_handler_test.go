@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/mailer"
+)
+
+type fakeMailer struct {
+	err  error
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestSendPasswordResetRetriesAfterMailFailure(t *testing.T) {
+	usr := database.User{ID: uuid.New(), Email: "alice@example.com"}
+
+	active := false
+	queries := map[string]fakeQuery{
+		"GetUserByEmail":     func([]driver.Value) (fakeResult, error) { return fakeUserRow(usr), nil },
+		"HasActiveUserToken": func([]driver.Value) (fakeResult, error) { return fakeRow(active), nil },
+		"InvalidateUserTokens": func([]driver.Value) (fakeResult, error) {
+			active = false
+			return fakeResult{}, nil
+		},
+		"CreateUserToken": func([]driver.Value) (fakeResult, error) {
+			active = true
+			return fakeResult{affected: 1}, nil
+		},
+	}
+	cfg := newFakeDB(t, queries)
+	m := &fakeMailer{err: errors.New("smtp down")}
+	cfg.mailer = m
+
+	if err := cfg.sendPasswordReset(context.Background(), usr.Email); err == nil {
+		t.Fatal("sendPasswordReset() with a failing mailer returned nil")
+	}
+	if active {
+		t.Error("undelivered token is still active")
+	}
+
+	m.err = nil
+	if err := cfg.sendPasswordReset(context.Background(), usr.Email); err != nil {
+		t.Fatalf("sendPasswordReset() = %v", err)
+	}
+	if len(m.sent) != 1 {
+		t.Errorf("sent %d emails after the mailer recovered, want 1", len(m.sent))
+	}
+}
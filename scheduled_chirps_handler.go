@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/entitlements"
+)
+
+// maxScheduleAhead is how far in the future a chirp can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// Scheduled chirp statuses the publisher sets on failure.
+const (
+	scheduledChirpPending = "pending"
+	scheduledChirpFailed  = "failed"
+)
+
+var (
+	errTooManyScheduledChirps = errors.New("too many scheduled chirps")
+	errSchedulingNotAllowed   = errors.New("plan no longer includes scheduled chirps")
+)
+
+// ScheduledChirp is a chirp waiting to be published, or one that failed
+// to publish. LastError says why it failed, or why the last attempt did.
+type ScheduledChirp struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	PublishAt time.Time `json:"publish_at"`
+	Status    string    `json:"status"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newScheduledChirp(s database.ScheduledChirp) ScheduledChirp {
+	return ScheduledChirp{
+		ID:        s.ID,
+		Body:      s.Body,
+		PublishAt: s.PublishAt,
+		Status:    s.Status,
+		LastError: s.LastError.String,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func (cfg *apiConfig) handlerCreateScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body      string    `json:"body"`
+		PublishAt time.Time `json:"publish_at"`
+	}
+
+	uid, err := cfg.requestUserID(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Token not valid", err)
+		return
+	}
+
+	var param parameters
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "decoding param", err)
+		return
+	}
+
+	now := time.Now()
+	if !param.PublishAt.After(now) || param.PublishAt.After(now.Add(maxScheduleAhead)) {
+		respondWithError(w, http.StatusBadRequest, "publish_at must be in the future and within a year", nil)
+		return
+	}
+
+	if err := cfg.checkEmailVerified(r.Context(), uid); err != nil {
+		if errors.Is(err, errEmailNotVerified) {
+			respondWithError(w, http.StatusForbidden, "Verify your email address before posting", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+
+	// The body is checked now so mistakes show up straight away, and again
+	// when it is published.
+	ent, err := cfg.requestEntitlements(r, uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch plan", err)
+		return
+	}
+	if _, err := cfg.validateChirpBody(param.Body, ent.MaxChirpLength); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// The author's row is locked while counting so concurrent requests
+	// can't both squeeze under the limit.
+	var sc database.ScheduledChirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if _, err := q.GetUserByIDForUpdate(r.Context(), uid); err != nil {
+			return err
+		}
+		pending, err := q.CountPendingScheduledChirps(r.Context(), uid)
+		if err != nil {
+			return err
+		}
+		if pending >= int64(ent.MaxScheduledChirps) {
+			return errTooManyScheduledChirps
+		}
+		sc, err = q.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+			ID:        uuid.New(),
+			UserID:    uid,
+			Body:      param.Body,
+			PublishAt: param.PublishAt,
+			CreatedAt: now,
+		})
+		return err
+	})
+	if errors.Is(err, errTooManyScheduledChirps) {
+		respondWithError(w, http.StatusForbidden, "Too many scheduled chirps", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't schedule chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newScheduledChirp(sc))
+}
+
+func (cfg *apiConfig) handlerListScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	uid, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	scheduled, err := cfg.db.ListScheduledChirps(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list scheduled chirps", err)
+		return
+	}
+
+	resp := make([]ScheduledChirp, 0, len(scheduled))
+	for _, s := range scheduled {
+		resp = append(resp, newScheduledChirp(s))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerDeleteScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	scheduledID, err := uuid.Parse(r.PathValue("scheduledID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scheduled chirp ID", err)
+		return
+	}
+
+	uid, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	n, err := cfg.db.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID:     scheduledID,
+		UserID: uid,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete scheduled chirp", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "scheduled chirp not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishNextScheduledChirp posts one due scheduled chirp, reporting whether
+// there was one. The author's plan, email verification and the moderation
+// rules are checked as they are at publish time; a chirp that no longer
+// passes is marked failed rather than retried. Other failures are retried
+// with chirpScheduler's policy and then marked failed too.
+func (cfg *apiConfig) publishNextScheduledChirp(ctx context.Context) (bool, error) {
+	var sc database.ScheduledChirp
+	var pubErr error
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		sc, err = q.ClaimDueScheduledChirp(ctx)
+		if err != nil {
+			return err
+		}
+		pubErr = cfg.publishScheduledChirp(ctx, q, sc)
+		return pubErr
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && sc.ID == uuid.Nil:
+		return false, nil
+	case err == nil:
+		cfg.webhookDispatcher.wake()
+		return true, nil
+	case pubErr == nil:
+		return false, err
+	}
+
+	// The failure is recorded outside the rolled-back transaction, pushing
+	// the chirp back so the next claim moves on to other work.
+	attempts := int(sc.Attempts) + 1
+	status := scheduledChirpPending
+	delay, retry := cfg.chirpScheduler.policy.Next(attempts)
+	var permanent permanentError
+	if !retry || errors.As(pubErr, &permanent) {
+		status = scheduledChirpFailed
+	}
+	if err := cfg.db.RecordScheduledChirpFailure(ctx, database.RecordScheduledChirpFailureParams{
+		ID:            sc.ID,
+		Status:        status,
+		Attempts:      int32(attempts),
+		LastError:     sql.NullString{String: pubErr.Error(), Valid: true},
+		NextAttemptAt: time.Now().Add(delay),
+	}); err != nil {
+		return false, err
+	}
+	log.Printf("Scheduled chirp %s attempt %d failed (%s): %v", sc.ID, attempts, status, pubErr)
+	return true, nil
+}
+
+func (cfg *apiConfig) publishScheduledChirp(ctx context.Context, q *database.Queries, sc database.ScheduledChirp) error {
+	ent, err := cfg.entitlementsFor(ctx, sc.UserID)
+	if err != nil {
+		return err
+	}
+	if !ent.Has(entitlements.FeatureScheduledChirps) {
+		return permanentError{errSchedulingNotAllowed}
+	}
+	if err := cfg.checkEmailVerified(ctx, sc.UserID); err != nil {
+		if errors.Is(err, errEmailNotVerified) {
+			return permanentError{err}
+		}
+		return err
+	}
+	moderated, err := cfg.validateChirpBody(sc.Body, ent.MaxChirpLength)
+	if err != nil {
+		return permanentError{err}
+	}
+
+	chrp, err := createChirp(ctx, q, database.CreateChirpParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    sc.UserID,
+		Kind:      chirpKindChirp,
+	}, moderated)
+	if err != nil {
+		return err
+	}
+	return q.MarkScheduledChirpPublished(ctx, database.MarkScheduledChirpPublishedParams{
+		ID:      sc.ID,
+		ChirpID: uuid.NullUUID{UUID: chrp.ID, Valid: true},
+	})
+}
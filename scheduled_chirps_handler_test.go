@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/entitlements"
+)
+
+func TestPublishScheduledChirpRechecksAuthor(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     entitlements.Entitlements
+		verified bool
+		wantErr  string
+	}{
+		{
+			name:     "Plan no longer schedules",
+			plan:     entitlements.Entitlements{MaxChirpLength: 140},
+			verified: true,
+			wantErr:  errSchedulingNotAllowed.Error(),
+		},
+		{
+			name:    "Email not verified",
+			plan:    entitlements.Entitlements{MaxChirpLength: 140, MaxScheduledChirps: 10},
+			wantErr: errEmailNotVerified.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usr := database.User{ID: uuid.New(), Email: "alice@example.com"}
+			if tt.verified {
+				usr.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			}
+			sc := database.ScheduledChirp{
+				ID:            uuid.New(),
+				UserID:        usr.ID,
+				Body:          "hello",
+				PublishAt:     time.Now(),
+				Status:        scheduledChirpPending,
+				CreatedAt:     time.Now(),
+				NextAttemptAt: time.Now(),
+			}
+
+			var recorded []driver.Value
+			queries := map[string]fakeQuery{
+				"ClaimDueScheduledChirp": func([]driver.Value) (fakeResult, error) {
+					return fakeRow(sc.ID.String(), sc.UserID.String(), sc.Body, sc.PublishAt, sc.Status,
+						nil, nil, sc.CreatedAt, int64(sc.Attempts), sc.NextAttemptAt), nil
+				},
+				"GetSubscriptionByUser": func([]driver.Value) (fakeResult, error) { return fakeNoRows(9), nil },
+				"GetUserByID":           func([]driver.Value) (fakeResult, error) { return fakeUserRow(usr), nil },
+				"RecordScheduledChirpFailure": func(args []driver.Value) (fakeResult, error) {
+					recorded = args
+					return fakeResult{affected: 1}, nil
+				},
+			}
+			cfg := newFakeDB(t, queries)
+			cfg.requireVerifiedEmail = true
+			cfg.entitlements = &entitlements.Config{Plans: map[string]entitlements.Entitlements{
+				entitlements.PlanFree: tt.plan,
+			}}
+			cfg.chirpScheduler = newBackgroundWorker(time.Minute)
+
+			found, err := cfg.publishNextScheduledChirp(context.Background())
+			if err != nil || !found {
+				t.Fatalf("publishNextScheduledChirp() = %v, %v; want true, nil", found, err)
+			}
+			if recorded == nil {
+				t.Fatal("failure wasn't recorded")
+			}
+			if status := recorded[1]; status != scheduledChirpFailed {
+				t.Errorf("status = %v, want %q", status, scheduledChirpFailed)
+			}
+			if lastErr := recorded[3]; lastErr != tt.wantErr {
+				t.Errorf("last_error = %v, want %q", lastErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// Session is one logged-in device. Its ID is the refresh token family, so it
+// stays the same across token rotations.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+func (cfg *apiConfig) handlerListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	rows, err := cfg.db.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list sessions", err)
+		return
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, v := range rows {
+		sessions = append(sessions, Session{
+			ID:         v.FamilyID,
+			CreatedAt:  v.SessionStartedAt,
+			LastUsedAt: v.LastUsedAt,
+			ExpiresAt:  v.ExpiresAt,
+			UserAgent:  v.UserAgent,
+			IPAddress:  v.IpAddress,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	// As on logout, the revoked session's access token can't be told apart
+	// from the others, so they all go and the remaining sessions refresh.
+	var n int64
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		n, err = q.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+			FamilyID: sessionID,
+			UserID:   userID,
+		})
+		if err != nil || n == 0 {
+			return err
+		}
+		_, err = q.BumpUserTokenVersion(r.Context(), userID)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "session not found", nil)
+		return
+	}
+	cfg.tokenVersions.forget(userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	// Bumping the token version also kills the access tokens already handed
+	// out, including the one on this request.
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if err := q.RevokeAllUserRefreshTokens(r.Context(), userID); err != nil {
+			return err
+		}
+		_, err := q.BumpUserTokenVersion(r.Context(), userID)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	cfg.tokenVersions.forget(userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientIP returns the address of the peer that sent r. Forwarding headers
+// are ignored because anyone can set them; put the server behind a proxy
+// that rewrites RemoteAddr if the real client address matters.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,26 @@
+-- name: LikeChirp :execrows
+INSERT INTO chirp_likes(
+    user_id, chirp_id, created_at
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+
+-- name: UnlikeChirp :execrows
+DELETE FROM chirp_likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+
+-- name: CountLikesForChirps :many
+SELECT chirp_id, COUNT(*) AS like_count
+FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY chirp_id;
+
+
+-- name: ListLikedChirpIDs :many
+SELECT chirp_id
+FROM chirp_likes
+WHERE user_id = sqlc.arg(user_id)
+  AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
@@ -0,0 +1,21 @@
+-- name: AddChirpMentions :exec
+INSERT INTO chirp_mentions(chirp_id, user_id, start_offset, end_offset, created_at)
+SELECT
+    sqlc.arg(chirp_id)::uuid,
+    unnest(sqlc.arg(user_ids)::uuid[]),
+    unnest(sqlc.arg(start_offsets)::integer[]),
+    unnest(sqlc.arg(end_offsets)::integer[]),
+    sqlc.arg(created_at)::timestamp;
+
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+
+-- name: ListMentionsForChirps :many
+SELECT m.chirp_id, m.user_id, u.username, m.start_offset, m.end_offset
+FROM chirp_mentions m
+JOIN users u ON u.id = m.user_id
+WHERE m.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY m.chirp_id, m.start_offset;
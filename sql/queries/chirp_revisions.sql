@@ -0,0 +1,13 @@
+-- name: CreateChirpRevision :one
+INSERT INTO chirp_revisions(
+    id, chirp_id, body, created_at
+) VALUES (
+    $1, $2, $3, $4
+)
+RETURNING *;
+
+
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY created_at DESC;
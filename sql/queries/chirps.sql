@@ -1,15 +1,45 @@
 -- name: CreateChirp :one
 INSERT INTO chirps(
-    id, created_at, updated_at, body, user_id
+    id, created_at, updated_at, body, user_id, reply_to_id, kind, quote_of
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6, $7, $8
 )
 RETURNING *;
 
 
 
--- name: ListChirps :many
-SELECT * FROM chirps;
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ListChirpsByAuthorAsc :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ListChirpsByAuthorDesc :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
 
 
 -- name: GetChirpByID :one
@@ -19,4 +49,162 @@ WHERE id=$1;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps 
-WHERE id=$1;
\ No newline at end of file
+WHERE id=$1;
+
+-- name: SearchChirps :many
+SELECT
+    sqlc.embed(c),
+    ts_rank_cd(c.body_tsv, query)::real AS rank,
+    ts_headline('english', c.body, query, sqlc.arg(headline_options)::text) AS headline
+FROM chirps c, websearch_to_tsquery('english', sqlc.arg(query)::text) query
+WHERE c.body_tsv @@ query
+  AND c.deleted_at IS NULL
+  AND (sqlc.narg(author_id)::uuid IS NULL OR c.user_id = sqlc.narg(author_id)::uuid)
+ORDER BY
+    CASE WHEN sqlc.arg(sort_order)::text = 'asc' THEN c.created_at END ASC,
+    CASE WHEN sqlc.arg(sort_order)::text = 'desc' THEN c.created_at END DESC,
+    rank DESC,
+    c.id ASC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: GetChirpByIDForUpdate :one
+SELECT * FROM chirps
+WHERE id=$1
+FOR UPDATE;
+
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW(), edited_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+
+-- name: TombstoneChirp :one
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS(
+    SELECT 1 FROM chirps WHERE reply_to_id = sqlc.arg(id)::uuid
+)::boolean AS has_replies;
+
+
+-- name: CountRepliesForChirps :many
+SELECT reply_to_id::uuid AS chirp_id, COUNT(*) AS reply_count
+FROM chirps
+WHERE reply_to_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+  AND deleted_at IS NULL
+GROUP BY reply_to_id;
+
+
+-- name: ListChirpAncestors :many
+WITH RECURSIVE ancestors(id, reply_to_id, depth) AS (
+    SELECT p.id, p.reply_to_id, 1
+    FROM chirps p
+    JOIN chirps child ON child.reply_to_id = p.id
+    WHERE child.id = sqlc.arg(id)
+    UNION ALL
+    SELECT p.id, p.reply_to_id, a.depth + 1
+    FROM chirps p
+    JOIN ancestors a ON p.id = a.reply_to_id
+    WHERE a.depth < sqlc.arg(max_depth)::integer
+)
+SELECT sqlc.embed(c), a.depth::integer AS depth
+FROM ancestors a
+JOIN chirps c ON c.id = a.id
+ORDER BY a.depth DESC;
+
+
+-- name: ListChirpDescendants :many
+WITH RECURSIVE descendants(id, depth) AS (
+    SELECT r.id, 1
+    FROM chirps r
+    WHERE r.reply_to_id = sqlc.arg(id)::uuid
+    UNION ALL
+    SELECT r.id, d.depth + 1
+    FROM chirps r
+    JOIN descendants d ON r.reply_to_id = d.id
+    WHERE d.depth < sqlc.arg(max_depth)::integer
+)
+SELECT sqlc.embed(c), d.depth::integer AS depth
+FROM descendants d
+JOIN chirps c ON c.id = d.id
+ORDER BY d.depth ASC, c.created_at ASC, c.id ASC
+LIMIT sqlc.arg(max_rows);
+
+
+
+-- name: ListChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+
+-- name: CreateRechirp :one
+INSERT INTO chirps(
+    id, created_at, updated_at, body, user_id, kind, rechirp_of
+) VALUES (
+    $1, $2, $3, '', $4, 'rechirp', $5
+)
+ON CONFLICT (user_id, rechirp_of) WHERE kind = 'rechirp' DO NOTHING
+RETURNING *;
+
+
+-- name: DeleteRechirp :one
+DELETE FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2 AND kind = 'rechirp'
+RETURNING id;
+
+
+-- name: DeleteRechirpsOf :many
+DELETE FROM chirps
+WHERE rechirp_of = $1 AND kind = 'rechirp'
+RETURNING id, user_id;
+
+
+-- name: CountRepostsForChirps :many
+SELECT
+    COALESCE(rechirp_of, quote_of)::uuid AS chirp_id,
+    COUNT(*) FILTER (WHERE kind = 'rechirp') AS rechirp_count,
+    COUNT(*) FILTER (WHERE kind = 'quote') AS quote_count
+FROM chirps
+WHERE (rechirp_of = ANY(sqlc.arg(chirp_ids)::uuid[]) OR quote_of = ANY(sqlc.arg(chirp_ids)::uuid[]))
+  AND deleted_at IS NULL
+GROUP BY COALESCE(rechirp_of, quote_of);
+
+
+-- name: ListTimeline :many
+SELECT * FROM chirps
+WHERE user_id IN (
+    SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(user_id)
+)
+  AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ListChirpsByHashtag :many
+SELECT c.* FROM chirps c
+JOIN chirp_hashtags h ON h.chirp_id = c.id
+WHERE h.tag = sqlc.arg(tag)
+  AND (h.created_at, h.chirp_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+  AND c.deleted_at IS NULL
+ORDER BY h.created_at DESC, h.chirp_id DESC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ListChirpsMentioningUser :many
+SELECT * FROM chirps
+WHERE id IN (
+    SELECT chirp_id FROM chirp_mentions WHERE chirp_mentions.user_id = sqlc.arg(user_id)
+)
+  AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+  AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
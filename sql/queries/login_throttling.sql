@@ -0,0 +1,38 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures
+WHERE scope = $1 AND key = $2;
+
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures(scope, key, failures, last_failed_at)
+VALUES ($1, $2, 1, $3)
+ON CONFLICT (scope, key) DO UPDATE
+SET failures = CASE
+        WHEN login_failures.last_failed_at < $4 THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failed_at = EXCLUDED.last_failed_at
+RETURNING *;
+
+
+-- name: LockLogin :exec
+UPDATE login_failures
+SET locked_until = $3
+WHERE scope = $1 AND key = $2;
+
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures
+WHERE scope = $1 AND key = $2;
+
+
+-- name: CreateLockoutEvent :exec
+INSERT INTO lockout_events(id, scope, key, failures, locked_until, ip_address, user_agent, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+
+-- name: ListLockoutEvents :many
+SELECT * FROM lockout_events
+WHERE (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
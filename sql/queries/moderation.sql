@@ -0,0 +1,24 @@
+-- name: ListModerationWords :many
+SELECT word, action
+FROM moderation_words
+ORDER BY word;
+
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags(id, chirp_id, reasons, created_at)
+VALUES ($1, $2, $3, $4);
+
+
+-- name: ListOpenChirpFlags :many
+SELECT *
+FROM chirp_flags
+WHERE resolved_at IS NULL
+    AND (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ResolveChirpFlag :execrows
+UPDATE chirp_flags
+SET resolved_at = NOW()
+WHERE id = $1 AND resolved_at IS NULL;
@@ -0,0 +1,17 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events(id, event_type, payload, created_at)
+VALUES ($1, $2, $3, $4);
+
+
+-- name: ClaimNextOutboxEvent :one
+SELECT * FROM outbox_events
+WHERE dispatched_at IS NULL
+ORDER BY created_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+
+-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox_events
+SET dispatched_at = NOW()
+WHERE id = $1;
@@ -1,27 +1,78 @@
 -- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens(
-    token,
+    id,
+    token_hash,
+    token_prefix,
     created_at,
     updated_at,
     user_id,
     expires_at,
-    revoked_at
+    revoked_at,
+    family_id,
+    session_started_at,
+    last_used_at,
+    user_agent,
+    ip_address
 ) VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
 ) RETURNING *;
 
 
 -- name: GetUserFromRefreshToken :one
 SELECT * FROM refresh_tokens 
-WHERE token = $1 AND revoked_at IS NULL;
+WHERE token_hash = $1 AND revoked_at IS NULL;
 
 
--- name: RevokeRefreshToken :exec
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1;
+
+
+-- name: ConsumeRefreshToken :one
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+
+-- name: RevokeRefreshToken :one
 UPDATE refresh_tokens 
 SET updated_at = Now(), revoked_at=Now()
-WHERE token=$1; 
\ No newline at end of file
+WHERE token_hash=$1
+RETURNING user_id;
+
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+
+-- name: ListActiveSessions :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY last_used_at DESC, family_id;
+
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+
+-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
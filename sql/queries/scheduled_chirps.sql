@@ -0,0 +1,40 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps(id, user_id, body, publish_at, status, created_at, next_attempt_at)
+VALUES ($1, $2, $3, $4, 'pending', $5, $4)
+RETURNING *;
+
+
+-- name: CountPendingScheduledChirps :one
+SELECT COUNT(*) AS pending FROM scheduled_chirps
+WHERE user_id = $1 AND status = 'pending';
+
+
+-- name: ListScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = $1 AND status IN ('pending', 'failed')
+ORDER BY publish_at, id;
+
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2 AND status IN ('pending', 'failed');
+
+
+-- name: ClaimDueScheduledChirp :one
+SELECT * FROM scheduled_chirps
+WHERE status = 'pending' AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+
+-- name: MarkScheduledChirpPublished :exec
+UPDATE scheduled_chirps
+SET status = 'published', chirp_id = $2, attempts = attempts + 1, last_error = NULL
+WHERE id = $1;
+
+
+-- name: RecordScheduledChirpFailure :exec
+UPDATE scheduled_chirps
+SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5
+WHERE id = $1;
@@ -0,0 +1,41 @@
+-- name: GetSubscriptionByUser :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+
+-- name: GetSubscriptionByUserForUpdate :one
+SELECT * FROM subscriptions
+WHERE user_id = $1
+FOR UPDATE;
+
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions(id, user_id, plan, status, current_period_start, current_period_end, last_event_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = EXCLUDED.status,
+    current_period_start = EXCLUDED.current_period_start,
+    current_period_end = EXCLUDED.current_period_end,
+    last_event_at = EXCLUDED.last_event_at,
+    updated_at = NOW()
+RETURNING *;
+
+
+-- name: CreateSubscriptionEvent :execrows
+INSERT INTO subscription_events(id, user_id, event_key, event, payload, occurred_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (event_key) DO NOTHING;
+
+
+-- name: MarkSubscriptionEventApplied :exec
+UPDATE subscription_events
+SET applied = true
+WHERE id = $1;
+
+
+-- name: ListSubscriptionEvents :many
+SELECT * FROM subscription_events
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
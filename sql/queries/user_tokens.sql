@@ -0,0 +1,23 @@
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens(id, user_id, purpose, token_hash, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+
+-- name: ConsumeUserToken :one
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+
+-- name: InvalidateUserTokens :exec
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+
+-- name: HasActiveUserToken :one
+SELECT EXISTS (
+    SELECT 1 FROM user_tokens
+    WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+) AS active;
@@ -4,13 +4,15 @@ INSERT INTO users(
     created_at,
     updated_at,
     email,
-    hashed_password
+    hashed_password,
+    username
 ) VALUES (
     $1,
     $2,
     $3,
     $4, 
-    $5
+    $5,
+    $6
 ) RETURNING *;
 
 -- name: DeleteUsers :exec
@@ -24,15 +26,78 @@ SELECT * from users WHERE email = $1;
 -- name: GetUserByID :one
 SELECT * FROM users WHERE id=$1;
 
+
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE;
+
+
 -- name: UpdateUser :one
 UPDATE users 
-SET email = $2, hashed_password = $3, updated_at = now()
-WHERE id=$1
+SET email_verified_at = CASE WHEN email = sqlc.arg(email) THEN email_verified_at END,
+    email = sqlc.arg(email),
+    hashed_password = sqlc.arg(hashed_password),
+    username = COALESCE(sqlc.narg(username), username),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
 
--- name: UpdateUserChirpyRed :one
+-- name: ListUsersByUsernames :many
+SELECT * FROM users
+WHERE lower(username) = ANY(sqlc.arg(usernames)::text[]);
+
+
+-- name: GetUserTokenVersion :one
+SELECT token_version FROM users WHERE id = $1;
+
+
+-- name: BumpUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1, updated_at = NOW()
+WHERE id = $1
+RETURNING token_version;
+
+
+-- name: MarkEmailVerified :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: SetUserTOTPSecret :execrows
+UPDATE users
+SET totp_secret = $2, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1 AND totp_enabled_at IS NULL;
+
+
+-- name: EnableUserTOTP :execrows
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL;
+
+
+-- name: AdvanceUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_last_step < $2;
+
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: RehashUserPassword :execrows
 UPDATE users
-set is_chirpy_red=true, updated_at=NOW()
-WHERE id=$1
-RETURNING *;
\ No newline at end of file
+SET hashed_password = sqlc.arg(new_hash)
+WHERE id = sqlc.arg(id) AND hashed_password = sqlc.arg(old_hash);
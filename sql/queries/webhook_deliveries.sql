@@ -0,0 +1,51 @@
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries(id, endpoint_id, event_id, status, next_attempt_at, created_at)
+VALUES ($1, $2, $3, 'pending', $4, $4)
+ON CONFLICT (endpoint_id, event_id) DO NOTHING;
+
+
+-- name: ClaimNextWebhookDelivery :one
+UPDATE webhook_deliveries d
+SET next_attempt_at = sqlc.arg(lease_until)
+FROM webhook_endpoints e, outbox_events o
+WHERE d.id = (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+  AND e.id = d.endpoint_id
+  AND o.id = d.event_id
+RETURNING d.id, d.attempts, d.event_id, e.url, e.secret, o.payload;
+
+
+-- name: RecordWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts(id, delivery_id, attempt, status_code, error, response_body, duration_ms, attempted_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2, attempts = $3, next_attempt_at = $4, delivered_at = $5
+WHERE id = $1;
+
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg(endpoint_id)
+  AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY attempt;
+
+
+-- name: RetryWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+WHERE id = $1 AND status = 'failed';
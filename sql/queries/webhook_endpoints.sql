@@ -0,0 +1,25 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints(id, url, secret, event_types, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $5)
+RETURNING *;
+
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1;
+
+
+-- name: ListWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+ORDER BY created_at, id;
+
+
+-- name: ListWebhookEndpointsForEvent :many
+SELECT * FROM webhook_endpoints
+WHERE sqlc.arg(event_type)::text = ANY(event_types)
+ORDER BY created_at, id;
+
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1;
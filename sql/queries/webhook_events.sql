@@ -0,0 +1,47 @@
+-- name: CreateWebhookEvent :execrows
+INSERT INTO webhook_events(id, source, delivery_id, payload, status, next_attempt_at, received_at)
+VALUES ($1, $2, $3, $4, 'pending', $5, $5)
+ON CONFLICT (source, delivery_id) DO NOTHING;
+
+
+-- name: ClaimNextWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE status = 'pending' AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at, received_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET status = 'processed', attempts = attempts + 1, last_error = NULL, processed_at = NOW()
+WHERE id = $1;
+
+
+-- name: RecordWebhookEventFailure :exec
+UPDATE webhook_events
+SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5
+WHERE id = $1;
+
+
+-- name: ListWebhookEventsByStatus :many
+SELECT * FROM webhook_events
+WHERE status = sqlc.arg(status)
+  AND (received_at, id) < (sqlc.arg(before_received_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY received_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: ReplayWebhookEvent :execrows
+UPDATE webhook_events
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), processed_at = NULL
+WHERE id = $1 AND status <> 'pending' AND octet_length(payload) > 0;
+
+
+-- name: ReplayWebhookEventsInRange :execrows
+UPDATE webhook_events
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), processed_at = NULL
+WHERE received_at >= sqlc.arg(received_from)
+  AND received_at < sqlc.arg(received_to)
+  AND status = ANY(sqlc.arg(statuses)::text[])
+  AND octet_length(payload) > 0;
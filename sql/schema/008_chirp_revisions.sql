@@ -0,0 +1,16 @@
+-- +goose up
+ALTER TABLE chirps ADD COLUMN edited_at TIMESTAMP;
+
+CREATE TABLE chirp_revisions(
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_revisions_chirp_id_created_at_idx ON chirp_revisions (chirp_id, created_at);
+
+
+-- +goose down
+DROP TABLE chirp_revisions;
+ALTER TABLE chirps DROP COLUMN IF EXISTS edited_at;
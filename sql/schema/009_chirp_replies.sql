@@ -0,0 +1,14 @@
+-- +goose up
+ALTER TABLE chirps
+ADD COLUMN reply_to_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_reply_to_id_created_at_idx ON chirps (reply_to_id, created_at)
+WHERE reply_to_id IS NOT NULL;
+
+
+-- +goose down
+DROP INDEX IF EXISTS chirps_reply_to_id_created_at_idx;
+ALTER TABLE chirps DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE chirps DROP COLUMN IF EXISTS reply_to_id;
@@ -0,0 +1,35 @@
+-- +goose up
+ALTER TABLE chirps
+ADD COLUMN kind TEXT NOT NULL DEFAULT 'chirp'
+CHECK (kind IN ('chirp', 'rechirp', 'quote'));
+
+-- Plain rechirps vanish with their original; quotes keep their own
+-- commentary and just lose the reference.
+ALTER TABLE chirps
+ADD COLUMN rechirp_of UUID REFERENCES chirps(id) ON DELETE CASCADE;
+
+ALTER TABLE chirps
+ADD COLUMN quote_of UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+ALTER TABLE chirps ADD CONSTRAINT chirps_rechirp_of_check
+CHECK ((kind = 'rechirp') = (rechirp_of IS NOT NULL));
+
+ALTER TABLE chirps ADD CONSTRAINT chirps_quote_of_check
+CHECK (kind = 'quote' OR quote_of IS NULL);
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_key ON chirps (user_id, rechirp_of)
+WHERE kind = 'rechirp';
+
+CREATE INDEX chirps_rechirp_of_idx ON chirps (rechirp_of) WHERE rechirp_of IS NOT NULL;
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of) WHERE quote_of IS NOT NULL;
+
+
+-- +goose down
+DROP INDEX IF EXISTS chirps_quote_of_idx;
+DROP INDEX IF EXISTS chirps_rechirp_of_idx;
+DROP INDEX IF EXISTS chirps_user_id_rechirp_of_key;
+ALTER TABLE chirps DROP CONSTRAINT IF EXISTS chirps_quote_of_check;
+ALTER TABLE chirps DROP CONSTRAINT IF EXISTS chirps_rechirp_of_check;
+ALTER TABLE chirps DROP COLUMN IF EXISTS quote_of;
+ALTER TABLE chirps DROP COLUMN IF EXISTS rechirp_of;
+ALTER TABLE chirps DROP COLUMN IF EXISTS kind;
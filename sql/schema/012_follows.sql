@@ -0,0 +1,18 @@
+-- +goose up
+CREATE TABLE follows(
+    follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+-- Keyset pagination of both follower and following lists. The timeline reads
+-- the primary key to find followees, then walks
+-- chirps_user_id_created_at_id_idx for each of them.
+CREATE INDEX follows_follower_id_created_at_idx ON follows (follower_id, created_at, followee_id);
+CREATE INDEX follows_followee_id_created_at_idx ON follows (followee_id, created_at, follower_id);
+
+
+-- +goose down
+DROP TABLE follows;
@@ -0,0 +1,21 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN username TEXT;
+
+CREATE UNIQUE INDEX users_username_key ON users (lower(username));
+
+CREATE TABLE chirp_mentions(
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL,
+    end_offset INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, start_offset)
+);
+
+CREATE INDEX chirp_mentions_user_id_created_at_idx ON chirp_mentions (user_id, created_at, chirp_id);
+
+
+-- +goose down
+DROP TABLE chirp_mentions;
+DROP INDEX IF EXISTS users_username_key;
+ALTER TABLE users DROP COLUMN IF EXISTS username;
@@ -0,0 +1,24 @@
+-- +goose up
+CREATE TABLE moderation_words(
+    word TEXT PRIMARY KEY,
+    action TEXT NOT NULL DEFAULT 'mask' CHECK (action IN ('mask', 'flag', 'reject')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO moderation_words(word, action)
+VALUES ('kerfuffle', 'mask'), ('sharbert', 'mask'), ('fornax', 'mask');
+
+CREATE TABLE chirp_flags(
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX chirp_flags_open_idx ON chirp_flags (created_at, id) WHERE resolved_at IS NULL;
+
+
+-- +goose down
+DROP TABLE chirp_flags;
+DROP TABLE moderation_words;
@@ -0,0 +1,14 @@
+-- +goose up
+ALTER TABLE refresh_tokens ADD COLUMN family_id UUID;
+
+-- Every existing token starts its own family.
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+
+-- +goose down
+DROP INDEX IF EXISTS refresh_tokens_family_id_idx;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS family_id;
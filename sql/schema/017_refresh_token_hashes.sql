@@ -0,0 +1,39 @@
+-- +goose up
+ALTER TABLE refresh_tokens
+    ADD COLUMN id UUID,
+    ADD COLUMN token_hash BYTEA,
+    ADD COLUMN token_prefix TEXT;
+
+-- Legacy rows are hashed so reuse detection still recognises them, but they
+-- are all revoked: anyone holding a plaintext copy from before this
+-- migration has to log in again.
+UPDATE refresh_tokens
+SET id = gen_random_uuid(),
+    token_hash = sha256(convert_to(token, 'UTF8')),
+    token_prefix = left(token, 8),
+    revoked_at = COALESCE(revoked_at, NOW()),
+    updated_at = NOW();
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_pkey;
+ALTER TABLE refresh_tokens DROP COLUMN token;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN id SET NOT NULL,
+    ALTER COLUMN token_hash SET NOT NULL,
+    ALTER COLUMN token_prefix SET NOT NULL,
+    ADD PRIMARY KEY (id),
+    ADD CONSTRAINT refresh_tokens_token_hash_key UNIQUE (token_hash);
+
+
+-- +goose down
+-- The plaintext tokens are gone; rows come back keyed by their hex digest,
+-- which no client holds, so every session is effectively logged out.
+ALTER TABLE refresh_tokens ADD COLUMN token TEXT;
+UPDATE refresh_tokens SET token = encode(token_hash, 'hex');
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_pkey;
+ALTER TABLE refresh_tokens
+    DROP COLUMN id,
+    DROP COLUMN token_hash,
+    DROP COLUMN token_prefix,
+    ALTER COLUMN token SET NOT NULL,
+    ADD PRIMARY KEY (token);
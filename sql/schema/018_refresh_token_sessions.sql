@@ -0,0 +1,25 @@
+-- +goose up
+ALTER TABLE refresh_tokens
+    ADD COLUMN session_started_at TIMESTAMP,
+    ADD COLUMN last_used_at TIMESTAMP,
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+
+UPDATE refresh_tokens
+SET session_started_at = created_at,
+    last_used_at = updated_at;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN session_started_at SET NOT NULL,
+    ALTER COLUMN last_used_at SET NOT NULL;
+
+CREATE INDEX refresh_tokens_user_id_active_idx ON refresh_tokens (user_id) WHERE revoked_at IS NULL;
+
+
+-- +goose down
+DROP INDEX IF EXISTS refresh_tokens_user_id_active_idx;
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS session_started_at,
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip_address;
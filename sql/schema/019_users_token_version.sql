@@ -0,0 +1,6 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+
+-- +goose down
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
@@ -0,0 +1,19 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+CREATE TABLE user_tokens(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL,
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX user_tokens_user_id_purpose_idx ON user_tokens (user_id, purpose) WHERE used_at IS NULL;
+
+
+-- +goose down
+DROP TABLE user_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,27 @@
+-- +goose up
+CREATE TABLE login_failures(
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE TABLE lockout_events(
+    id UUID PRIMARY KEY,
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMP NOT NULL,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX lockout_events_created_at_idx ON lockout_events (created_at DESC, id DESC);
+
+
+-- +goose down
+DROP TABLE lockout_events;
+DROP TABLE login_failures;
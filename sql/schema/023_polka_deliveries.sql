@@ -0,0 +1,11 @@
+-- +goose up
+CREATE TABLE polka_deliveries(
+    id TEXT PRIMARY KEY,
+    received_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX polka_deliveries_received_at_idx ON polka_deliveries (received_at);
+
+
+-- +goose down
+DROP TABLE polka_deliveries;
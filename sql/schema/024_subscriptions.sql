@@ -0,0 +1,48 @@
+-- +goose up
+CREATE TABLE subscriptions(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP,
+    last_event_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE subscription_events(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_key TEXT UNIQUE,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    applied BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX subscription_events_user_id_idx ON subscription_events (user_id, created_at DESC);
+
+-- Existing Chirpy Red members keep their status with an open-ended period
+-- until Polka next renews or cancels them.
+INSERT INTO subscriptions(id, user_id, plan, status, current_period_start, current_period_end, last_event_at, created_at, updated_at)
+SELECT gen_random_uuid(), id, 'red', 'active', updated_at, NULL, updated_at, NOW(), NOW()
+FROM users
+WHERE is_chirpy_red;
+
+ALTER TABLE users DROP COLUMN is_chirpy_red;
+
+
+-- +goose down
+ALTER TABLE users ADD COLUMN is_chirpy_red BOOLEAN DEFAULT false NOT NULL;
+
+UPDATE users SET is_chirpy_red = true
+WHERE id IN (
+    SELECT user_id FROM subscriptions
+    WHERE status <> 'ended'
+      AND (current_period_end IS NULL OR current_period_end > NOW())
+);
+
+DROP TABLE subscription_events;
+DROP TABLE subscriptions;
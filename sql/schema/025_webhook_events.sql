@@ -0,0 +1,39 @@
+-- +goose up
+CREATE TABLE webhook_events(
+    id UUID PRIMARY KEY,
+    source TEXT NOT NULL,
+    delivery_id TEXT,
+    payload BYTEA NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT,
+    received_at TIMESTAMP NOT NULL,
+    processed_at TIMESTAMP,
+    UNIQUE (source, delivery_id)
+);
+
+CREATE INDEX webhook_events_pending_idx ON webhook_events (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_events_status_received_at_idx ON webhook_events (status, received_at DESC, id DESC);
+
+-- Delivery IDs are now kept with each stored event.
+INSERT INTO webhook_events(id, source, delivery_id, payload, status, next_attempt_at, received_at, processed_at)
+SELECT gen_random_uuid(), 'polka', id, ''::bytea, 'processed', received_at, received_at, received_at
+FROM polka_deliveries;
+
+DROP TABLE polka_deliveries;
+
+
+-- +goose down
+CREATE TABLE polka_deliveries(
+    id TEXT PRIMARY KEY,
+    received_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX polka_deliveries_received_at_idx ON polka_deliveries (received_at);
+
+INSERT INTO polka_deliveries(id, received_at)
+SELECT delivery_id, received_at FROM webhook_events
+WHERE source = 'polka' AND delivery_id IS NOT NULL;
+
+DROP TABLE webhook_events;
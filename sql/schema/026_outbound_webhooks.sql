@@ -0,0 +1,57 @@
+-- +goose up
+CREATE TABLE webhook_endpoints(
+    id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- The transactional outbox: handlers write events in the same transaction
+-- as the change they describe, and the dispatcher fans them out to
+-- endpoints afterwards.
+CREATE TABLE outbox_events(
+    id UUID PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    dispatched_at TIMESTAMP
+);
+
+CREATE INDEX outbox_events_undispatched_idx ON outbox_events (created_at, id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE webhook_deliveries(
+    id UUID PRIMARY KEY,
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_endpoint_created_at_idx ON webhook_deliveries (endpoint_id, created_at DESC, id DESC);
+
+CREATE TABLE webhook_delivery_attempts(
+    id UUID PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    response_body TEXT NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    attempted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhook_delivery_attempts_delivery_idx ON webhook_delivery_attempts (delivery_id, attempt);
+
+
+-- +goose down
+DROP TABLE webhook_delivery_attempts;
+DROP TABLE webhook_deliveries;
+DROP TABLE outbox_events;
+DROP TABLE webhook_endpoints;
@@ -0,0 +1,20 @@
+-- +goose up
+CREATE TABLE scheduled_chirps(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    publish_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX scheduled_chirps_due_idx ON scheduled_chirps (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX scheduled_chirps_user_idx ON scheduled_chirps (user_id, publish_at) WHERE status IN ('pending', 'failed');
+
+
+-- +goose down
+DROP TABLE scheduled_chirps;
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/subscription"
+)
+
+const subscriptionEventHistoryLimit = 50
+
+// Subscription is the caller's subscription with its recent events.
+type Subscription struct {
+	Plan               string              `json:"plan"`
+	Status             string              `json:"status"`
+	Active             bool                `json:"active"`
+	CurrentPeriodStart time.Time           `json:"current_period_start"`
+	CurrentPeriodEnd   *time.Time          `json:"current_period_end"`
+	Events             []SubscriptionEvent `json:"events"`
+}
+
+// SubscriptionEvent is one billing event received for a user.
+type SubscriptionEvent struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Applied    bool      `json:"applied"`
+}
+
+func fromDBSubscription(s database.Subscription) subscription.Subscription {
+	return subscription.Subscription{
+		Plan:        s.Plan,
+		Status:      subscription.Status(s.Status),
+		PeriodStart: s.CurrentPeriodStart,
+		PeriodEnd:   s.CurrentPeriodEnd.Time,
+		LastEventAt: s.LastEventAt,
+	}
+}
+
+// userPlan returns the plan of the user's active subscription, or the free
+// plan when there is none.
+func (cfg *apiConfig) userPlan(ctx context.Context, userID uuid.UUID) (string, error) {
+	s, err := cfg.db.GetSubscriptionByUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return subscription.PlanFree, nil
+		}
+		return "", err
+	}
+	if !fromDBSubscription(s).Active(time.Now()) {
+		return subscription.PlanFree, nil
+	}
+	return s.Plan, nil
+}
+
+// chirpyRed reports whether the user is on a paid plan right now.
+func (cfg *apiConfig) chirpyRed(ctx context.Context, userID uuid.UUID) (bool, error) {
+	plan, err := cfg.userPlan(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return plan != subscription.PlanFree, nil
+}
+
+// newUserWithSubscription is newUser plus the derived Chirpy Red status.
+func (cfg *apiConfig) newUserWithSubscription(ctx context.Context, u database.User) (User, error) {
+	usr := newUser(u)
+	red, err := cfg.chirpyRed(ctx, u.ID)
+	if err != nil {
+		return User{}, err
+	}
+	usr.IsChirpyRed = red
+	return usr, nil
+}
+
+// applySubscriptionEvent records ev in the user's history and applies it.
+// An event whose key was seen before is skipped, so retried deliveries are
+// harmless. Events that don't change anything, such as one older than the
+// last applied or a cancellation for a user who never subscribed, are kept
+// in the history as not applied. Gaining a plan writes a user.upgraded event
+// to the outbox.
+func applySubscriptionEvent(ctx context.Context, q *database.Queries, userID uuid.UUID, key string, payload []byte, ev subscription.Event) error {
+	eventID := uuid.New()
+	n, err := q.CreateSubscriptionEvent(ctx, database.CreateSubscriptionEventParams{
+		ID:         eventID,
+		UserID:     userID,
+		EventKey:   sql.NullString{String: key, Valid: key != ""},
+		Event:      string(ev.Type),
+		Payload:    string(payload),
+		OccurredAt: ev.At,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	current, err := q.GetSubscriptionByUserForUpdate(ctx, userID)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	next, err := subscription.Apply(fromDBSubscription(current), exists, ev)
+	switch {
+	case errors.Is(err, subscription.ErrStaleEvent),
+		errors.Is(err, subscription.ErrNoSubscription),
+		errors.Is(err, subscription.ErrUnknownEvent):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	id := current.ID
+	if !exists {
+		id = uuid.New()
+	}
+	saved, err := q.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+		ID:                 id,
+		UserID:             userID,
+		Plan:               next.Plan,
+		Status:             string(next.Status),
+		CurrentPeriodStart: next.PeriodStart,
+		CurrentPeriodEnd:   sql.NullTime{Time: next.PeriodEnd, Valid: !next.PeriodEnd.IsZero()},
+		LastEventAt:        next.LastEventAt,
+	})
+	if err != nil {
+		return err
+	}
+	if err := q.MarkSubscriptionEventApplied(ctx, eventID); err != nil {
+		return err
+	}
+
+	// user.upgraded fires when the user gains a plan they didn't have: a
+	// new or lapsed subscription starting, or a switch to another plan.
+	wasActive := exists && fromDBSubscription(current).Active(ev.At)
+	if next.Active(ev.At) && (!wasActive || current.Plan != next.Plan) {
+		upgraded := UserUpgradedEvent{UserID: userID, Plan: saved.Plan}
+		if saved.CurrentPeriodEnd.Valid {
+			upgraded.CurrentPeriodEnd = &saved.CurrentPeriodEnd.Time
+		}
+		return emitEvent(ctx, q, outboxUserUpgraded, upgraded)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerGetMySubscription(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	s, err := cfg.db.GetSubscriptionByUser(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "No subscription", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch subscription", err)
+		return
+	}
+
+	events, err := cfg.db.ListSubscriptionEvents(r.Context(), database.ListSubscriptionEventsParams{
+		UserID: userID,
+		Limit:  subscriptionEventHistoryLimit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch subscription events", err)
+		return
+	}
+
+	resp := Subscription{
+		Plan:               s.Plan,
+		Status:             s.Status,
+		Active:             fromDBSubscription(s).Active(time.Now()),
+		CurrentPeriodStart: s.CurrentPeriodStart,
+		Events:             make([]SubscriptionEvent, 0, len(events)),
+	}
+	if s.CurrentPeriodEnd.Valid {
+		resp.CurrentPeriodEnd = &s.CurrentPeriodEnd.Time
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, SubscriptionEvent{
+			Event:      e.Event,
+			OccurredAt: e.OccurredAt,
+			Applied:    e.Applied,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+)
+
+var errStaleAccessToken = errors.New("access token has been revoked")
+
+// maxTokenVersionEntries bounds the cache; expired entries are swept once
+// it grows past this.
+const maxTokenVersionEntries = 10000
+
+// tokenVersionCache remembers users' token versions for a short TTL so
+// authenticating a request rarely touches the database. A bump made by this
+// process is seen immediately; one made by another instance is seen once
+// the entry expires.
+type tokenVersionCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]tokenVersionEntry
+}
+
+type tokenVersionEntry struct {
+	version   int32
+	expiresAt time.Time
+}
+
+func newTokenVersionCache(ttl time.Duration) *tokenVersionCache {
+	return &tokenVersionCache{
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]tokenVersionEntry),
+	}
+}
+
+func (c *tokenVersionCache) get(userID uuid.UUID) (int32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[userID]
+	if !ok || time.Now().After(e.expiresAt) {
+		return 0, false
+	}
+	return e.version, true
+}
+
+func (c *tokenVersionCache) set(userID uuid.UUID, version int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= maxTokenVersionEntries {
+		for id, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[userID] = tokenVersionEntry{version: version, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *tokenVersionCache) forget(userID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+// userTokenVersion returns the user's current token version, from the cache
+// when possible.
+func (cfg *apiConfig) userTokenVersion(ctx context.Context, userID uuid.UUID) (int32, error) {
+	if v, ok := cfg.tokenVersions.get(userID); ok {
+		return v, nil
+	}
+	v, err := cfg.db.GetUserTokenVersion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	cfg.tokenVersions.set(userID, v)
+	return v, nil
+}
+
+// authenticate returns the user behind the request's bearer access token,
+// rejecting tokens issued before the user's last credential change or
+// forced logout.
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	claims, err := cfg.jwtKeys.ParseAccessToken(token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	current, err := cfg.userTokenVersion(r.Context(), claims.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if claims.TokenVersion != current {
+		return uuid.Nil, errStaleAccessToken
+	}
+	return claims.UserID, nil
+}
+
+// accessTokenTTL is how long an access token is valid.
+const accessTokenTTL = time.Hour
+
+// makeAccessToken issues an access token at the user's current token
+// version.
+func (cfg *apiConfig) makeAccessToken(userID uuid.UUID, tokenVersion int32) (string, error) {
+	return cfg.jwtKeys.MakeAccessToken(userID, tokenVersion, accessTokenTTL)
+}
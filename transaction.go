@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// withTx runs fn with a Queries bound to a single transaction. The
+// transaction commits when fn returns nil and rolls back otherwise.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(cfg.db.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// issueUserToken creates a single-use token for purpose, invalidating any
+// unused ones the user already has for it. Only the token's digest is
+// stored; the caller mails the returned plaintext.
+func (cfg *apiConfig) issueUserToken(ctx context.Context, userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	token, err := auth.MakeOneTimeToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		if err := q.InvalidateUserTokens(ctx, database.InvalidateUserTokensParams{
+			UserID:  userID,
+			Purpose: purpose,
+		}); err != nil {
+			return err
+		}
+		return q.CreateUserToken(ctx, database.CreateUserTokenParams{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: auth.HashOneTimeToken(token),
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
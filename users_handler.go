@@ -1,28 +1,37 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/natnael-alemayehu/chirpy/internal/auth"
 	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/entities"
+	"github.com/natnael-alemayehu/chirpy/internal/mailer"
 )
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	Password    string    `json:"-"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Username      string    `json:"username,omitempty"`
+	Password      string    `json:"-"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	EmailVerified bool      `json:"email_verified"`
+	TwoFactor     bool      `json:"two_factor_enabled"`
 }
 
 func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request) {
 	type parameter struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 
 	var param parameter
@@ -32,7 +41,18 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	hash, err := auth.HashPassword(param.Password)
+	if !mailer.ValidAddress(param.Email) {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address", nil)
+		return
+	}
+
+	username, err := parseUsername(param.Username)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	hash, err := auth.HashPasswordWithParams(param.Password, cfg.passwordParams)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating hash", err)
 		return
@@ -44,30 +64,49 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		UpdatedAt:      time.Now(),
 		Email:          param.Email,
 		HashedPassword: hash,
+		Username:       username,
 	}
 
-	usr, err := cfg.db.CreateUser(r.Context(), dbparam)
+	var usr database.User
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		usr, err = q.CreateUser(r.Context(), dbparam)
+		if err != nil {
+			return err
+		}
+		return emitEvent(r.Context(), q, outboxUserCreated, UserCreatedEvent{
+			ID:        usr.ID,
+			Username:  usr.Username.String,
+			CreatedAt: usr.CreatedAt,
+		})
+	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "Email or username already taken", err)
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Create User error", err)
 		return
 	}
+	cfg.webhookDispatcher.wake()
 
-	respondWithJSON(w, http.StatusCreated, User{
-		ID:          usr.ID,
-		CreatedAt:   usr.CreatedAt,
-		UpdatedAt:   usr.UpdatedAt,
-		Email:       usr.Email,
-		IsChirpyRed: usr.IsChirpyRed,
-	})
+	// The account exists either way; a failed send can be retried through
+	// POST /api/users/verify/resend.
+	if err := cfg.sendEmailVerification(r.Context(), usr); err != nil {
+		log.Printf("Couldn't start email verification for %s: %v", usr.ID, err)
+	}
+
+	respondWithJSON(w, http.StatusCreated, newUser(usr))
 }
 
 func (cfg *apiConfig) hanlderUpdateUser(w http.ResponseWriter, r *http.Request) {
 	type parameter struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Username string `json:"username"`
 	}
 	type response struct {
 		User
+		Token string `json:"token,omitempty"`
 	}
 
 	var param parameter
@@ -76,41 +115,117 @@ func (cfg *apiConfig) hanlderUpdateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	uid, err := cfg.authenticate(r)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Token error", err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
 		return
 	}
 
-	uid, err := auth.ValidateJWT(token, cfg.secret)
+	if !mailer.ValidAddress(param.Email) {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address", nil)
+		return
+	}
+
+	username, err := parseUsername(param.Username)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	hash, err := auth.HashPassword(param.Password)
+	current, err := cfg.db.GetUserByID(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch user", err)
+		return
+	}
+	samePassword, err := auth.CheckPasswordHash(param.Password, current.HashedPassword)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "compare hash err", err)
+		return
+	}
+
+	hash, err := auth.HashPasswordWithParams(param.Password, cfg.passwordParams)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Password hashing failed", err)
 		return
 	}
 
-	updatedUser, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             uid,
-		Email:          param.Email,
-		HashedPassword: hash,
+	// A new password signs out every session: refresh tokens are revoked and
+	// every access token issued so far is invalidated. The caller gets a
+	// fresh access token in the response so this request's client isn't
+	// logged out on the spot.
+	var updatedUser database.User
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		updatedUser, err = q.UpdateUser(r.Context(), database.UpdateUserParams{
+			ID:             uid,
+			Email:          param.Email,
+			HashedPassword: hash,
+			Username:       username,
+		})
+		if err != nil || samePassword {
+			return err
+		}
+		if err := q.RevokeAllUserRefreshTokens(r.Context(), uid); err != nil {
+			return err
+		}
+		updatedUser.TokenVersion, err = q.BumpUserTokenVersion(r.Context(), uid)
+		return err
 	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "Email or username already taken", err)
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed Writing to database", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:          updatedUser.ID,
-			CreatedAt:   updatedUser.CreatedAt,
-			UpdatedAt:   updatedUser.UpdatedAt,
-			Email:       updatedUser.Email,
-			IsChirpyRed: updatedUser.IsChirpyRed,
-		},
-	})
+	if updatedUser.Email != current.Email {
+		if err := cfg.sendEmailVerification(r.Context(), updatedUser); err != nil {
+			log.Printf("Couldn't start email verification for %s: %v", uid, err)
+		}
+	}
+
+	user, err := cfg.newUserWithSubscription(r.Context(), updatedUser)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch subscription", err)
+		return
+	}
+
+	resp := response{User: user}
+	if !samePassword {
+		cfg.tokenVersions.forget(uid)
+		resp.Token, err = cfg.makeAccessToken(uid, updatedUser.TokenVersion)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "JWT creation error", err)
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// newUser converts a database user. IsChirpyRed depends on the user's
+// subscription and is filled in by newUserWithSubscription.
+func newUser(u database.User) User {
+	return User{
+		ID:            u.ID,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+		Email:         u.Email,
+		Username:      u.Username.String,
+		EmailVerified: u.EmailVerifiedAt.Valid,
+		TwoFactor:     u.TotpEnabledAt.Valid,
+	}
+}
+
+// parseUsername validates an optional username from a request body. An empty
+// string means "not provided".
+func parseUsername(s string) (sql.NullString, error) {
+	if s == "" {
+		return sql.NullString{}, nil
+	}
+	if !entities.ValidUsername(s) {
+		return sql.NullString{}, errors.New("Username must be 3-30 letters, digits or underscores")
+	}
+	return sql.NullString{String: s, Valid: true}, nil
 }
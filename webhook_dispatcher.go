@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/webhook"
+)
+
+// Outbound delivery statuses.
+const (
+	webhookDeliveryPending   = "pending"
+	webhookDeliveryDelivered = "delivered"
+	webhookDeliveryFailed    = "failed"
+)
+
+// webhookDeliveryLease is how long a claimed delivery is hidden from other
+// dispatchers. It has to outlast the HTTP call; if a dispatcher dies
+// mid-delivery the lease runs out and the delivery is tried again.
+const webhookDeliveryLease = time.Minute
+
+// newWebhookSender returns the sender for outbound deliveries. Unless
+// allowPrivate is set it refuses to connect to anything but public
+// addresses.
+func newWebhookSender(allowPrivate bool) *webhook.Sender {
+	timeout := durationEnv("WEBHOOK_DELIVERY_TIMEOUT", 10*time.Second)
+	if allowPrivate {
+		return &webhook.Sender{Client: &http.Client{Timeout: timeout}}
+	}
+	return &webhook.Sender{Client: webhook.NewPublicClient(timeout)}
+}
+
+// dispatchNextWebhook does one unit of outbound work: it fans out the next
+// outbox event if there is one, and otherwise sends the next due delivery.
+func (cfg *apiConfig) dispatchNextWebhook(ctx context.Context) (bool, error) {
+	found, err := cfg.fanOutNextOutboxEvent(ctx)
+	if err != nil || found {
+		return found, err
+	}
+	return cfg.sendNextWebhookDelivery(ctx)
+}
+
+// fanOutNextOutboxEvent creates a delivery of the oldest undispatched event
+// for every endpoint subscribed to its type.
+func (cfg *apiConfig) fanOutNextOutboxEvent(ctx context.Context) (bool, error) {
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		ev, err := q.ClaimNextOutboxEvent(ctx)
+		if err != nil {
+			return err
+		}
+		endpoints, err := q.ListWebhookEndpointsForEvent(ctx, ev.EventType)
+		if err != nil {
+			return err
+		}
+		for _, e := range endpoints {
+			if err := q.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+				ID:         uuid.New(),
+				EndpointID: e.ID,
+				EventID:    ev.ID,
+				CreatedAt:  time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+		return q.MarkOutboxEventDispatched(ctx, ev.ID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// sendNextWebhookDelivery sends one due delivery and logs the attempt.
+// Failures are retried with webhookDispatcher's policy and then marked
+// failed, where an admin can retry them.
+func (cfg *apiConfig) sendNextWebhookDelivery(ctx context.Context) (bool, error) {
+	d, err := cfg.db.ClaimNextWebhookDelivery(ctx, time.Now().Add(webhookDeliveryLease))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	now := time.Now()
+	a := cfg.webhookSender.Send(ctx, d.Url, d.EventID.String(), now, d.Payload, []byte(d.Secret))
+	attempts := int(d.Attempts) + 1
+
+	update := database.UpdateWebhookDeliveryParams{
+		ID:            d.ID,
+		Status:        webhookDeliveryDelivered,
+		Attempts:      int32(attempts),
+		NextAttemptAt: now,
+		DeliveredAt:   sql.NullTime{Time: now, Valid: true},
+	}
+	attempt := database.RecordWebhookDeliveryAttemptParams{
+		ID:           uuid.New(),
+		DeliveryID:   d.ID,
+		Attempt:      int32(attempts),
+		StatusCode:   sql.NullInt32{Int32: int32(a.StatusCode), Valid: a.StatusCode != 0},
+		ResponseBody: a.Response,
+		DurationMs:   int32(a.Duration.Milliseconds()),
+		AttemptedAt:  now,
+	}
+	if !a.OK() {
+		attempt.Error = sql.NullString{String: a.Err.Error(), Valid: true}
+		update.DeliveredAt = sql.NullTime{}
+		update.Status = webhookDeliveryFailed
+		if delay, retry := cfg.webhookDispatcher.policy.Next(attempts); retry {
+			update.Status = webhookDeliveryPending
+			update.NextAttemptAt = now.Add(delay)
+		}
+		log.Printf("Webhook delivery %s to %s attempt %d failed (%s): %v", d.ID, d.Url, attempts, update.Status, a.Err)
+	}
+
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		if err := q.RecordWebhookDeliveryAttempt(ctx, attempt); err != nil {
+			return err
+		}
+		return q.UpdateWebhookDelivery(ctx, update)
+	})
+	return err == nil, err
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/webhook"
+)
+
+// WebhookEndpoint is a registered receiver of outbound webhooks. Secret is
+// only returned when the endpoint is created.
+type WebhookEndpoint struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event's delivery to one endpoint.
+type WebhookDelivery struct {
+	ID            uuid.UUID  `json:"id"`
+	EndpointID    uuid.UUID  `json:"endpoint_id"`
+	EventID       uuid.UUID  `json:"event_id"`
+	Status        string     `json:"status"`
+	Attempts      int32      `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// WebhookDeliveryAttempt is one logged try at a delivery. StatusCode is
+// null when the endpoint couldn't be reached.
+type WebhookDeliveryAttempt struct {
+	Attempt     int32     `json:"attempt"`
+	StatusCode  *int32    `json:"status_code"`
+	Error       string    `json:"error,omitempty"`
+	Response    string    `json:"response"`
+	DurationMs  int32     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+func newWebhookEndpoint(e database.WebhookEndpoint) WebhookEndpoint {
+	return WebhookEndpoint{
+		ID:         e.ID,
+		URL:        e.Url,
+		EventTypes: e.EventTypes,
+		CreatedAt:  e.CreatedAt,
+	}
+}
+
+func (cfg *apiConfig) handlerCreateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	type parameter struct {
+		URL        string   `json:"url"`
+		EventTypes []string `json:"event_types"`
+	}
+
+	var param parameter
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "json not formatted correctly", err)
+		return
+	}
+
+	// Plain http and internal addresses are only accepted for local
+	// development. Deliveries check the address again when connecting.
+	if err := webhook.ValidateEndpointURL(param.URL, cfg.platform == "dev"); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if !cfg.webhookAllowPrivate {
+		if err := webhook.CheckEndpointHost(r.Context(), nil, param.URL); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	if len(param.EventTypes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "event_types is required", nil)
+		return
+	}
+	for _, t := range param.EventTypes {
+		if !slices.Contains(outboxEventTypes, t) {
+			respondWithError(w, http.StatusBadRequest, "Unknown event type "+t, nil)
+			return
+		}
+	}
+	slices.Sort(param.EventTypes)
+	param.EventTypes = slices.Compact(param.EventTypes)
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate secret", err)
+		return
+	}
+
+	e, err := cfg.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		ID:         uuid.New(),
+		Url:        param.URL,
+		Secret:     secret,
+		EventTypes: param.EventTypes,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create webhook endpoint", err)
+		return
+	}
+
+	resp := newWebhookEndpoint(e)
+	resp.Secret = e.Secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) handlerListWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := cfg.db.ListWebhookEndpoints(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list webhook endpoints", err)
+		return
+	}
+
+	resp := make([]WebhookEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		resp = append(resp, newWebhookEndpoint(e))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerDeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid endpoint ID", err)
+		return
+	}
+
+	n, err := cfg.db.DeleteWebhookEndpoint(r.Context(), endpointID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete webhook endpoint", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "webhook endpoint not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid endpoint ID", err)
+		return
+	}
+	if _, err := cfg.db.GetWebhookEndpoint(r.Context(), endpointID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "webhook endpoint not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't fetch webhook endpoint", err)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	deliveries, err := cfg.db.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		EndpointID:      endpointID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		PageLimit:       limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list webhook deliveries", err)
+		return
+	}
+
+	if len(deliveries) > int(limit) {
+		deliveries = deliveries[:limit]
+		last := deliveries[len(deliveries)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	resp := make([]WebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		delivery := WebhookDelivery{
+			ID:            d.ID,
+			EndpointID:    d.EndpointID,
+			EventID:       d.EventID,
+			Status:        d.Status,
+			Attempts:      d.Attempts,
+			NextAttemptAt: d.NextAttemptAt,
+			CreatedAt:     d.CreatedAt,
+		}
+		if d.DeliveredAt.Valid {
+			delivery.DeliveredAt = &d.DeliveredAt.Time
+		}
+		resp = append(resp, delivery)
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerListWebhookDeliveryAttempts(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID", err)
+		return
+	}
+
+	attempts, err := cfg.db.ListWebhookDeliveryAttempts(r.Context(), deliveryID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list delivery attempts", err)
+		return
+	}
+
+	resp := make([]WebhookDeliveryAttempt, 0, len(attempts))
+	for _, a := range attempts {
+		attempt := WebhookDeliveryAttempt{
+			Attempt:     a.Attempt,
+			Error:       a.Error.String,
+			Response:    a.ResponseBody,
+			DurationMs:  a.DurationMs,
+			AttemptedAt: a.AttemptedAt,
+		}
+		if a.StatusCode.Valid {
+			attempt.StatusCode = &a.StatusCode.Int32
+		}
+		resp = append(resp, attempt)
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerRetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID", err)
+		return
+	}
+
+	n, err := cfg.db.RetryWebhookDelivery(r.Context(), deliveryID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retry webhook delivery", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "failed webhook delivery not found", nil)
+		return
+	}
+	cfg.webhookDispatcher.wake()
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+)
+
+// WebhookEvent is a stored inbound webhook as shown to admins.
+type WebhookEvent struct {
+	ID          uuid.UUID  `json:"id"`
+	Source      string     `json:"source"`
+	DeliveryID  string     `json:"delivery_id,omitempty"`
+	Status      string     `json:"status"`
+	Attempts    int32      `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	ReceivedAt  time.Time  `json:"received_at"`
+	ProcessedAt *time.Time `json:"processed_at"`
+	Payload     string     `json:"payload"`
+}
+
+func (cfg *apiConfig) handlerListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = webhookEventFailed
+	case webhookEventPending, webhookEventProcessed, webhookEventFailed:
+	default:
+		respondWithError(w, http.StatusBadRequest, "status must be pending, processed or failed", nil)
+		return
+	}
+
+	limit, cursor, err := parsePageParams(r, true)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	events, err := cfg.db.ListWebhookEventsByStatus(r.Context(), database.ListWebhookEventsByStatusParams{
+		Status:           status,
+		BeforeReceivedAt: cursor.CreatedAt,
+		BeforeID:         cursor.ID,
+		PageLimit:        limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list webhook events", err)
+		return
+	}
+
+	if len(events) > int(limit) {
+		events = events[:limit]
+		last := events[len(events)-1]
+		setNextPageHeaders(w, r, pageCursor{CreatedAt: last.ReceivedAt, ID: last.ID})
+	}
+
+	resp := make([]WebhookEvent, 0, len(events))
+	for _, e := range events {
+		ev := WebhookEvent{
+			ID:         e.ID,
+			Source:     e.Source,
+			DeliveryID: e.DeliveryID.String,
+			Status:     e.Status,
+			Attempts:   e.Attempts,
+			LastError:  e.LastError.String,
+			ReceivedAt: e.ReceivedAt,
+			Payload:    string(e.Payload),
+		}
+		if e.ProcessedAt.Valid {
+			ev.ProcessedAt = &e.ProcessedAt.Time
+		}
+		resp = append(resp, ev)
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerReplayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(r.PathValue("eventID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid event ID", err)
+		return
+	}
+
+	// Deliveries migrated from polka_deliveries kept only their ID and have
+	// no payload to replay, so they are never requeued.
+	n, err := cfg.db.ReplayWebhookEvent(r.Context(), eventID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't replay webhook event", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "webhook event not found, already pending or without a payload", nil)
+		return
+	}
+	cfg.webhookWorker.wake()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerReplayWebhookEvents requeues the failed events received in a time
+// range, and the processed ones too when asked. Replaying processed events
+// is safe: every stored event applies under a stable key (see
+// processPolkaEvent), and a key is only ever applied once. Deliveries
+// migrated from polka_deliveries are skipped, as they were stored without
+// a payload.
+func (cfg *apiConfig) handlerReplayWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	type parameter struct {
+		From             time.Time `json:"from"`
+		To               time.Time `json:"to"`
+		IncludeProcessed bool      `json:"include_processed"`
+	}
+	type response struct {
+		Replayed int64 `json:"replayed"`
+	}
+
+	var param parameter
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		respondWithError(w, http.StatusBadRequest, "json not formatted correctly", err)
+		return
+	}
+	if param.From.IsZero() || param.To.IsZero() || !param.From.Before(param.To) {
+		respondWithError(w, http.StatusBadRequest, "from and to must be set and from must be before to", errors.New("invalid range"))
+		return
+	}
+
+	statuses := []string{webhookEventFailed}
+	if param.IncludeProcessed {
+		statuses = append(statuses, webhookEventProcessed)
+	}
+
+	n, err := cfg.db.ReplayWebhookEventsInRange(r.Context(), database.ReplayWebhookEventsInRangeParams{
+		ReceivedFrom: param.From,
+		ReceivedTo:   param.To,
+		Statuses:     statuses,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't replay webhook events", err)
+		return
+	}
+	if n > 0 {
+		cfg.webhookWorker.wake()
+	}
+
+	respondWithJSON(w, http.StatusOK, response{Replayed: n})
+}
@@ -1,63 +1,104 @@
 package main
 
 import (
+	"crypto/subtle"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/natnael-alemayehu/chirpy/internal/auth"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/webhook"
 )
 
-func (cfg *apiConfig) handlerUpdateSubscription(w http.ResponseWriter, r *http.Request) {
-	type parameter struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+const maxWebhookBodyBytes = 1 << 20
+
+const webhookSourcePolka = "polka"
+
+// newPolkaVerifier reads POLKA_WEBHOOK_SECRETS, a comma-separated list of
+// the secrets Polka may sign with. Without secrets it returns nil, which
+// leaves the legacy API key check in place, but only when
+// POLKA_ALLOW_API_KEY says so: that mode has no replay protection and
+// shouldn't be reached by forgetting a variable.
+func newPolkaVerifier(polkaKey string) (*webhook.Verifier, error) {
+	var secrets [][]byte
+	for _, s := range strings.Split(os.Getenv("POLKA_WEBHOOK_SECRETS"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			secrets = append(secrets, []byte(s))
+		}
 	}
+	if len(secrets) == 0 {
+		if !boolEnv("POLKA_ALLOW_API_KEY", false) {
+			return nil, errors.New("POLKA_WEBHOOK_SECRETS must be set, or POLKA_ALLOW_API_KEY=true to accept the legacy API key")
+		}
+		if polkaKey == "" {
+			return nil, errors.New("POLKAKEY must be set when POLKA_ALLOW_API_KEY=true")
+		}
+		log.Print("POLKA_WEBHOOK_SECRETS is unset; Polka webhooks are checked against POLKAKEY only")
+		return nil, nil
+	}
+	return &webhook.Verifier{
+		Secrets:   secrets,
+		Tolerance: durationEnv("POLKA_WEBHOOK_TOLERANCE", webhook.DefaultTolerance),
+	}, nil
+}
 
+// authenticatePolka checks the delivery's signature and returns its ID. Without
+// signing secrets it falls back to the shared API key, and the ID is empty.
+func (cfg *apiConfig) authenticatePolka(r *http.Request, body []byte) (string, error) {
+	if cfg.polkaVerifier != nil {
+		return cfg.polkaVerifier.Verify(r.Header, body)
+	}
 	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized request: API key err", err)
-		return
+		return "", err
 	}
-
-	if apiKey != cfg.polkaKey {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized request: API key err", err)
-		return
+	if subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.polkaKey)) != 1 {
+		return "", errors.New("API key mismatch")
 	}
+	return "", nil
+}
 
-	data, err := io.ReadAll(r.Body)
+func (cfg *apiConfig) handlerUpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	// The signature covers the exact bytes sent, so read them before
+	// anything parses the body.
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "failed to read body", err)
-	}
-
-	var param parameter
-	if err := json.Unmarshal(data, &param); err != nil {
-		respondWithError(w, http.StatusBadRequest, "json not formatted correctly", err)
+		respondWithError(w, http.StatusBadRequest, "failed to read body", err)
 		return
 	}
 
-	if param.Event != "user.upgraded" {
-		respondWithJSON(w, http.StatusNoContent, struct{}{})
-	}
-
-	userID, err := uuid.Parse(param.Data.UserID)
+	deliveryID, err := cfg.authenticatePolka(r, data)
 	if err != nil {
-		respondWithError(w, http.StatusBadGateway, "user_id not formatted correctly", err)
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized request: webhook signature err", err)
+		return
 	}
 
-	_, err = cfg.db.UpdateUserChirpyRed(r.Context(), userID)
+	// The delivery is stored as received and acknowledged; the webhook
+	// worker applies it. Nothing Polka sends is lost to a failed write
+	// further down the line, and failures can be replayed.
+	n, err := cfg.db.CreateWebhookEvent(r.Context(), database.CreateWebhookEventParams{
+		ID:         uuid.New(),
+		Source:     webhookSourcePolka,
+		DeliveryID: sql.NullString{String: deliveryID, Valid: deliveryID != ""},
+		Payload:    data,
+		ReceivedAt: time.Now(),
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			respondWithError(w, http.StatusNotFound, "", err)
-			return
-		}
 		respondWithError(w, http.StatusInternalServerError, "Error saving data to db", err)
 		return
 	}
+	// A duplicate is Polka retrying after losing our acknowledgement. The
+	// delivery is already stored, so acknowledge it again.
+	if n > 0 {
+		cfg.webhookWorker.wake()
+	}
 
-	respondWithJSON(w, http.StatusNoContent, struct{}{})
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natnael-alemayehu/chirpy/internal/database"
+	"github.com/natnael-alemayehu/chirpy/internal/subscription"
+)
+
+// Webhook event statuses. Pending events wait for the worker; failed ones
+// ran out of attempts or can never succeed and wait for an admin replay.
+const (
+	webhookEventPending   = "pending"
+	webhookEventProcessed = "processed"
+	webhookEventFailed    = "failed"
+)
+
+// permanentError marks a failure that retrying won't fix, such as a
+// malformed payload. The event is dead-lettered straight away.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// processNextWebhookEvent applies one due inbound event, reporting whether
+// there was one. The event is processed in the same transaction that marks
+// it done, so it takes effect exactly once.
+func (cfg *apiConfig) processNextWebhookEvent(ctx context.Context) (bool, error) {
+	var ev database.WebhookEvent
+	var procErr error
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		ev, err = q.ClaimNextWebhookEvent(ctx)
+		if err != nil {
+			return err
+		}
+		if procErr = processWebhookEvent(ctx, q, ev); procErr != nil {
+			return procErr
+		}
+		return q.MarkWebhookEventProcessed(ctx, ev.ID)
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && ev.ID == uuid.Nil:
+		return false, nil
+	case err == nil:
+		// Applying the event may have written to the outbox.
+		cfg.webhookDispatcher.wake()
+		return true, nil
+	case procErr == nil:
+		return false, err
+	}
+
+	// The transaction rolled back whatever the event had done, so it is
+	// safe to try again later.
+	attempts := int(ev.Attempts) + 1
+	status := webhookEventPending
+	delay, retry := cfg.webhookWorker.policy.Next(attempts)
+	var permanent permanentError
+	if !retry || errors.As(procErr, &permanent) {
+		status = webhookEventFailed
+	}
+	if err := cfg.db.RecordWebhookEventFailure(ctx, database.RecordWebhookEventFailureParams{
+		ID:            ev.ID,
+		Status:        status,
+		Attempts:      int32(attempts),
+		LastError:     sql.NullString{String: procErr.Error(), Valid: true},
+		NextAttemptAt: time.Now().Add(delay),
+	}); err != nil {
+		return false, err
+	}
+	log.Printf("Webhook event %s attempt %d failed (%s): %v", ev.ID, attempts, status, procErr)
+	return true, nil
+}
+
+func processWebhookEvent(ctx context.Context, q *database.Queries, ev database.WebhookEvent) error {
+	switch ev.Source {
+	case webhookSourcePolka:
+		return processPolkaEvent(ctx, q, ev)
+	}
+	return permanentError{fmt.Errorf("unknown webhook source %q", ev.Source)}
+}
+
+func processPolkaEvent(ctx context.Context, q *database.Queries, ev database.WebhookEvent) error {
+	type parameter struct {
+		ID         string    `json:"id"`
+		Event      string    `json:"event"`
+		OccurredAt time.Time `json:"occurred_at"`
+		Data       struct {
+			UserID      string    `json:"user_id"`
+			Plan        string    `json:"plan"`
+			PeriodStart time.Time `json:"period_start"`
+			PeriodEnd   time.Time `json:"period_end"`
+		} `json:"data"`
+	}
+
+	var param parameter
+	if err := json.Unmarshal(ev.Payload, &param); err != nil {
+		return permanentError{fmt.Errorf("json not formatted correctly: %w", err)}
+	}
+
+	subEvent := subscription.Event{
+		Type:        subscription.EventType(param.Event),
+		Plan:        param.Data.Plan,
+		PeriodStart: param.Data.PeriodStart,
+		PeriodEnd:   param.Data.PeriodEnd,
+		At:          param.OccurredAt,
+	}
+	if subEvent.At.IsZero() {
+		subEvent.At = ev.ReceivedAt
+	}
+	if !subEvent.Type.Known() {
+		return nil
+	}
+
+	userID, err := uuid.Parse(param.Data.UserID)
+	if err != nil {
+		return permanentError{fmt.Errorf("user_id not formatted correctly: %w", err)}
+	}
+	if _, err := q.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return permanentError{fmt.Errorf("user %s not found", userID)}
+		}
+		return err
+	}
+
+	// Events are deduplicated by Polka's event ID when it sends one, then by
+	// the delivery ID. Legacy API-key deliveries have neither, so they fall
+	// back to the stored event's own ID; that at least makes replaying the
+	// same stored event a no-op.
+	eventKey := param.ID
+	if eventKey == "" {
+		eventKey = ev.DeliveryID.String
+	}
+	if eventKey == "" {
+		eventKey = "webhook-event:" + ev.ID.String()
+	}
+	return applySubscriptionEvent(ctx, q, userID, eventKey, ev.Payload, subEvent)
+}
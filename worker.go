@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/natnael-alemayehu/chirpy/internal/webhook"
+)
+
+// backgroundWorker runs one step of background work after another until
+// there is nothing left, then sleeps until its next tick or a wake.
+// Several instances can run at once: steps claim their rows with FOR
+// UPDATE SKIP LOCKED, so no two workers take the same one.
+type backgroundWorker struct {
+	interval time.Duration
+	policy   webhook.RetryPolicy
+	wakeCh   chan struct{}
+}
+
+func newBackgroundWorker(interval time.Duration) *backgroundWorker {
+	return &backgroundWorker{
+		interval: interval,
+		policy:   webhook.DefaultRetryPolicy,
+		wakeCh:   make(chan struct{}, 1),
+	}
+}
+
+// wake asks the worker to look for work now rather than at its next tick.
+func (w *backgroundWorker) wake() {
+	select {
+	case w.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// run calls step until it reports there was nothing to do, then waits.
+func (w *backgroundWorker) run(ctx context.Context, name string, step func(context.Context) (bool, error)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			found, err := step(ctx)
+			if err != nil {
+				log.Printf("%s: %v", name, err)
+				break
+			}
+			if !found {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wakeCh:
+		}
+	}
+}